@@ -0,0 +1,241 @@
+package gee
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMultipartMemory is the memory used to parse multipart forms by default,
+// the rest of the files are stored on disk
+const defaultMultipartMemory = 32 << 20
+
+// Bind picks a binding by method and Content-Type, see ShouldBind.
+// On failure it aborts the chain with a 400 problem, or 413 when the body
+// is over the engine limit, and returns the error.
+func (c *Context) Bind(obj interface{}) error {
+	if err := c.ShouldBind(obj); err != nil {
+		if err = c.bodyError(err); err != ErrBodyTooLarge {
+			c.AbortWithProblem(http.StatusBadRequest, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// ShouldBind binds a JSON body for application/json requests,
+// the query string for GET/HEAD/DELETE and the form otherwise
+func (c *Context) ShouldBind(obj interface{}) error {
+	if c.Method == "GET" || c.Method == "HEAD" || c.Method == "DELETE" {
+		return c.ShouldBindQuery(obj)
+	}
+	contentType := c.Req.Header.Get("Content-Type")
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	if strings.TrimSpace(contentType) == "application/json" {
+		return c.ShouldBindJSON(obj)
+	}
+	return c.ShouldBindForm(obj)
+}
+
+// ShouldBindJSON decodes the request body as JSON into obj, then validates it
+func (c *Context) ShouldBindJSON(obj interface{}) error {
+	if c.Req.Body == nil {
+		return errors.New("gee: empty request body")
+	}
+	if err := json.NewDecoder(c.Req.Body).Decode(obj); err != nil {
+		return err
+	}
+	return Validate(obj)
+}
+
+// ShouldBindQuery maps the query string into obj using the `form` tag, then validates it
+func (c *Context) ShouldBindQuery(obj interface{}) error {
+	return bindValues(obj, c.Req.URL.Query(), "form")
+}
+
+// ShouldBindUri maps the route params into obj using the `uri` tag, then validates it
+func (c *Context) ShouldBindUri(obj interface{}) error {
+	values := make(map[string][]string, len(c.Params))
+	for _, p := range c.Params {
+		values[p.Key] = []string{p.Value}
+	}
+	return bindValues(obj, values, "uri")
+}
+
+// ShouldBindForm maps the url-encoded or multipart form, including the query
+// string, into obj using the `form` tag, then validates it
+func (c *Context) ShouldBindForm(obj interface{}) error {
+	if strings.HasPrefix(c.Req.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := c.Req.ParseMultipartForm(c.multipartMemory()); err != nil {
+			return err
+		}
+	} else if err := c.Req.ParseForm(); err != nil {
+		return err
+	}
+	return bindValues(obj, c.Req.Form, "form")
+}
+
+func bindValues(obj interface{}, values map[string][]string, tag string) error {
+	if err := mapValues(obj, values, tag); err != nil {
+		return err
+	}
+	return Validate(obj)
+}
+
+// mapValues sets the fields of the struct obj points to from values,
+// keyed by the tag name or the field name. Fields tagged "-" are skipped,
+// anonymous and untagged struct fields are mapped recursively.
+func mapValues(obj interface{}, values map[string][]string, tag string) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("gee: binding requires a non-nil pointer to a struct")
+	}
+	return mapStruct(v.Elem(), values, tag)
+}
+
+func mapStruct(v reflect.Value, values map[string][]string, tag string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		name := field.Tag.Get(tag)
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if name == "" && field.Type.Kind() == reflect.Struct && field.Type != timeType {
+			if err := mapStruct(fv, values, tag); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		vs, ok := values[name]
+		if !ok || len(vs) == 0 {
+			if def := field.Tag.Get("default"); def != "" {
+				vs = []string{def}
+			} else {
+				continue
+			}
+		}
+		if err := setField(fv, field, vs); err != nil {
+			return fmt.Errorf("gee: field %s: %v", field.Name, err)
+		}
+	}
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func setField(fv reflect.Value, field reflect.StructField, vs []string) error {
+	switch fv.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(fv.Type().Elem())
+		if err := setField(elem.Elem(), field, vs); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	case reflect.Slice:
+		slice := reflect.MakeSlice(fv.Type(), len(vs), len(vs))
+		for i, s := range vs {
+			if err := setValue(slice.Index(i), field, s); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+	return setValue(fv, field, vs[0])
+}
+
+// setValue converts s to the type of fv, time.Time uses the `time_format`
+// tag (default RFC3339) and accepts unix seconds when the format is "unix"
+func setValue(fv reflect.Value, field reflect.StructField, s string) error {
+	if fv.Type() == timeType {
+		if s == "" {
+			return nil
+		}
+		format := field.Tag.Get("time_format")
+		if format == "" {
+			format = time.RFC3339
+		}
+		if format == "unix" {
+			sec, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return err
+			}
+			fv.Set(reflect.ValueOf(time.Unix(sec, 0)))
+			return nil
+		}
+		t, err := time.Parse(format, s)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		if s == "" {
+			s = "false"
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			s = "0"
+		}
+		if fv.Type() == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return err
+			}
+			fv.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			s = "0"
+		}
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			s = "0"
+		}
+		f, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
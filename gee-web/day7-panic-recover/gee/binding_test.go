@@ -0,0 +1,92 @@
+package gee
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindUser struct {
+	ID       int       `uri:"id" form:"id" binding:"required,min=1"`
+	Name     string    `form:"name" json:"name" binding:"required,max=8"`
+	Email    string    `form:"email" json:"email" binding:"omitempty,email"`
+	Role     string    `form:"role" json:"role" binding:"oneof=admin user"`
+	Active   bool      `form:"active" json:"active"`
+	Tags     []string  `form:"tag" json:"tags" binding:"max=2"`
+	Birthday time.Time `form:"birthday" time_format:"2006-01-02" json:"-"`
+	Page     *int      `form:"page" json:"-"`
+	Size     int       `form:"size" default:"20" json:"-"`
+}
+
+func TestShouldBindQuery(t *testing.T) {
+	req := httptest.NewRequest("GET", "/?id=1&name=geektutu&role=admin&active=true&tag=a&tag=b&birthday=2019-08-17&page=3", nil)
+	c := newContext(httptest.NewRecorder(), req)
+
+	var u bindUser
+	if err := c.ShouldBindQuery(&u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "geektutu" || u.Role != "admin" || !u.Active || len(u.Tags) != 2 || u.Size != 20 {
+		t.Fatalf("unexpected binding %+v", u)
+	}
+	if u.Page == nil || *u.Page != 3 || u.Birthday.Day() != 17 {
+		t.Fatalf("unexpected pointer/time binding %+v", u)
+	}
+}
+
+func TestShouldBindUriAndForm(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("name", "jack")
+	mw.WriteField("role", "user")
+	mw.Close()
+
+	r := New()
+	var u bindUser
+	var uriErr, formErr error
+	r.POST("/users/:id", func(c *Context) {
+		uriErr = c.ShouldBindUri(&u)
+		formErr = c.ShouldBind(&u)
+	})
+	req := httptest.NewRequest("POST", "/users/42", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	// the uri struct isn't complete yet, Name is required
+	if _, ok := uriErr.(ValidationErrors); !ok {
+		t.Fatalf("uri binding should report validation errors, got %v", uriErr)
+	}
+	if formErr != nil || u.ID != 42 || u.Name != "jack" || u.Role != "user" {
+		t.Fatalf("unexpected binding %+v, err %v", u, formErr)
+	}
+}
+
+func TestBindValidationErrors(t *testing.T) {
+	body := `{"name":"a-very-long-name","email":"not-an-email","role":"root","tags":["a","b","c"]}`
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	w := httptest.NewRecorder()
+	c := newContext(w, req)
+
+	var u bindUser
+	err := c.Bind(&u)
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("should return ValidationErrors, got %v", err)
+	}
+	var tags []string
+	for _, e := range errs {
+		tags = append(tags, e.Field+":"+e.Tag)
+	}
+	if got := strings.Join(tags, ","); got != "ID:required,Name:max,Email:email,Role:oneof,Tags:max" {
+		t.Fatalf("unexpected field errors %s", got)
+	}
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != "application/problem+json" ||
+		!strings.Contains(w.Body.String(), `"detail":"`) {
+		t.Fatalf("Bind should respond with a 400 problem, got %d %q", w.Code, w.Body.String())
+	}
+}
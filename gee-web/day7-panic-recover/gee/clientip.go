@@ -0,0 +1,74 @@
+package gee
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SetTrustedProxies sets the IPs or CIDR ranges of the proxies whose
+// X-Forwarded-For and X-Real-IP headers are used by ClientIP, none by default
+func (engine *Engine) SetTrustedProxies(proxies ...string) error {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return fmt.Errorf("gee: invalid proxy IP %q", proxy)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return fmt.Errorf("gee: invalid proxy CIDR %q", proxy)
+		}
+		nets = append(nets, ipNet)
+	}
+	engine.trustedProxies = nets
+	return nil
+}
+
+func (engine *Engine) isTrustedProxy(ip net.IP) bool {
+	for _, ipNet := range engine.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the IP of the client. When the request comes from a trusted
+// proxy, it is the rightmost untrusted address of X-Forwarded-For, or X-Real-IP.
+func (c *Context) ClientIP() string {
+	remote := c.Req.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	ip := net.ParseIP(remote)
+	if ip == nil || c.engine == nil || !c.engine.isTrustedProxy(ip) {
+		return remote
+	}
+
+	if forwarded := c.Req.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			hopIP := net.ParseIP(hop)
+			if hopIP == nil {
+				break
+			}
+			if i == 0 || !c.engine.isTrustedProxy(hopIP) {
+				return hop
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(c.Req.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return remote
+}
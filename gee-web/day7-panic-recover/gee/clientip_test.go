@@ -0,0 +1,43 @@
+package gee
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	r := New()
+	if err := r.SetTrustedProxies("10.0.0.0/8", "192.168.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Fatal("invalid CIDR should fail")
+	}
+	r.SetTrustedProxies("10.0.0.0/8", "192.168.1.1")
+
+	cases := []struct {
+		remote, forwarded, realIP, want string
+	}{
+		{"1.2.3.4:5678", "", "", "1.2.3.4"},
+		{"1.2.3.4:5678", "6.6.6.6", "", "1.2.3.4"},
+		{"10.0.0.1:5678", "6.6.6.6, 5.5.5.5, 10.0.0.2", "", "5.5.5.5"},
+		{"192.168.1.1:5678", "10.1.1.1", "", "10.1.1.1"},
+		{"10.0.0.1:5678", "", "7.7.7.7", "7.7.7.7"},
+		{"10.0.0.1:5678", "bogus", "", "10.0.0.1"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tc.remote
+		if tc.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tc.forwarded)
+		}
+		if tc.realIP != "" {
+			req.Header.Set("X-Real-IP", tc.realIP)
+		}
+		c := newContext(httptest.NewRecorder(), req)
+		c.engine = r
+		if ip := c.ClientIP(); ip != tc.want {
+			t.Fatalf("ClientIP of %+v should be %s, got %s", tc, tc.want, ip)
+		}
+	}
+}
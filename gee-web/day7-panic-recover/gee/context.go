@@ -1,23 +1,35 @@
 package gee
 
 import (
-	"encoding/json"
-	"fmt"
+	"context"
 	"net/http"
+	"net/url"
+	"time"
 )
 
 type H map[string]interface{}
 
+// Context is recycled once the request is handled,
+// it must not be kept or used by goroutines outliving the handler
 type Context struct {
 	// origin objects
-	Writer http.ResponseWriter
-	Req    *http.Request
+	Writer    ResponseWriter
+	Req       *http.Request
+	writermem responseWriter
 	// request info
 	Path   string
 	Method string
-	Params map[string]string
+	Params Params
+	// pattern of the matched route
+	fullPath string
+	// template set used by HTML, see UseTemplates
+	templateSet string
 	// response info
 	StatusCode int
+	// errors attached by c.Error
+	Errors []*Error
+	// per-request values shared by handlers, see Set and Get
+	Keys map[string]interface{}
 	// middleware
 	handlers []HandlerFunc
 	index    int
@@ -26,13 +38,26 @@ type Context struct {
 }
 
 func newContext(w http.ResponseWriter, req *http.Request) *Context {
-	return &Context{
-		Path:   req.URL.Path,
-		Method: req.Method,
-		Req:    req,
-		Writer: w,
-		index:  -1,
-	}
+	c := &Context{}
+	c.reset(w, req)
+	return c
+}
+
+// reset prepares a pooled Context for a new request, keeping the Params capacity
+func (c *Context) reset(w http.ResponseWriter, req *http.Request) {
+	c.writermem.reset(w)
+	c.Writer = &c.writermem
+	c.Req = req
+	c.Path = req.URL.Path
+	c.Method = req.Method
+	c.Params = c.Params[:0]
+	c.fullPath = ""
+	c.templateSet = ""
+	c.StatusCode = 0
+	c.Errors = c.Errors[:0]
+	c.Keys = nil
+	c.handlers = nil
+	c.index = -1
 }
 
 func (c *Context) Next() {
@@ -49,8 +74,13 @@ func (c *Context) Fail(code int, err string) {
 }
 
 func (c *Context) Param(key string) string {
-	value, _ := c.Params[key]
-	return value
+	return c.Params.ByName(key)
+}
+
+// FullPath returns the pattern of the matched route, such as /user/:id,
+// empty when no route matched
+func (c *Context) FullPath() string {
+	return c.fullPath
 }
 
 func (c *Context) PostForm(key string) string {
@@ -70,32 +100,95 @@ func (c *Context) SetHeader(key string, value string) {
 	c.Writer.Header().Set(key, value)
 }
 
-func (c *Context) String(code int, format string, values ...interface{}) {
-	c.SetHeader("Content-Type", "text/plain")
-	c.Status(code)
-	c.Writer.Write([]byte(fmt.Sprintf(format, values...)))
+// Set stores value under key for the rest of the request
+func (c *Context) Set(key string, value interface{}) {
+	if c.Keys == nil {
+		c.Keys = make(map[string]interface{})
+	}
+	c.Keys[key] = value
+}
+
+// Get returns the value stored under key by Set
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	value, exists = c.Keys[key]
+	return
+}
+
+// MustGet returns the value stored under key, it panics if there is none
+func (c *Context) MustGet(key string) interface{} {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic("gee: key " + key + " does not exist")
+}
+
+// Copy returns a copy of c that stays valid after the handlers return, for
+// goroutines and work outliving the request. It keeps the request, params,
+// keys and errors of c, but has no handlers and its Writer must not be used.
+func (c *Context) Copy() *Context {
+	cp := c.fork(nil, c.Req)
+	cp.writermem = responseWriter{status: c.Writer.Status(), size: c.Writer.Size()}
+	cp.Writer = &cp.writermem
+	cp.StatusCode = c.StatusCode
+	cp.handlers = nil
+	cp.index = -1
+	return cp
+}
+
+// Deadline, Done, Err and Value make Context a context.Context delegating
+// to the request, so that it can be passed to calls honoring cancellation.
+// c is recycled for another request once the handlers return, pass c.Copy()
+// to goroutines and work outliving them.
+
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	return c.Req.Context().Deadline()
 }
 
-func (c *Context) JSON(code int, obj interface{}) {
-	c.SetHeader("Content-Type", "application/json")
-	c.Status(code)
-	encoder := json.NewEncoder(c.Writer)
-	if err := encoder.Encode(obj); err != nil {
-		http.Error(c.Writer, err.Error(), 500)
+// Done is closed when the client goes away, the request times out or its
+// handlers return. It is only valid until then, see Copy.
+func (c *Context) Done() <-chan struct{} {
+	return c.Req.Context().Done()
+}
+
+func (c *Context) Err() error {
+	return c.Req.Context().Err()
+}
+
+// Value returns the value stored by Set for string keys, or the value of the request context
+func (c *Context) Value(key interface{}) interface{} {
+	if k, ok := key.(string); ok {
+		if value, exists := c.Get(k); exists {
+			return value
+		}
 	}
+	return c.Req.Context().Value(key)
 }
 
-func (c *Context) Data(code int, data []byte) {
-	c.Status(code)
-	c.Writer.Write(data)
+var _ context.Context = &Context{}
+
+// Cookie returns the unescaped value of the request cookie name
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return url.QueryUnescape(cookie.Value)
 }
 
-// HTML template render
-// refer https://golang.org/pkg/html/template/
-func (c *Context) HTML(code int, name string, data interface{}) {
-	c.SetHeader("Content-Type", "text/html")
-	c.Status(code)
-	if err := c.engine.htmlTemplates.ExecuteTemplate(c.Writer, name, data); err != nil {
-		c.Fail(500, err.Error())
+// SetCookie adds a Set-Cookie header, value is escaped.
+// maxAge < 0 deletes the cookie, 0 makes it a session cookie.
+func (c *Context) SetCookie(name, value string, maxAge int, path, domain string, secure, httpOnly bool) {
+	if path == "" {
+		path = "/"
 	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    url.QueryEscape(value),
+		MaxAge:   maxAge,
+		Path:     path,
+		Domain:   domain,
+		Secure:   secure,
+		HttpOnly: httpOnly,
+		SameSite: http.SameSiteLaxMode,
+	})
 }
@@ -0,0 +1,188 @@
+package gee
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+)
+
+// ErrorType tells whether an error may be shown to clients
+type ErrorType uint8
+
+const (
+	// ErrorTypePrivate errors are only logged, clients see the status text
+	ErrorTypePrivate ErrorType = iota
+	// ErrorTypePublic errors are rendered as the problem detail
+	ErrorTypePublic
+)
+
+// Error is an error attached to a Context by c.Error
+type Error struct {
+	Err    error
+	Status int // HTTP status of the response, 0 for 500
+	Type   ErrorType
+	Meta   H // rendered as extension members of the problem
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// SetStatus sets the HTTP status the error maps to
+func (e *Error) SetStatus(code int) *Error {
+	e.Status = code
+	return e
+}
+
+// SetType sets whether the error is public or private
+func (e *Error) SetType(t ErrorType) *Error {
+	e.Type = t
+	return e
+}
+
+// SetMeta adds key to the metadata of the error
+func (e *Error) SetMeta(key string, value interface{}) *Error {
+	if e.Meta == nil {
+		e.Meta = H{}
+	}
+	e.Meta[key] = value
+	return e
+}
+
+// Error attaches err to the context and returns it for further settings,
+// an *Error is attached as is. It doesn't stop the chain or write anything,
+// see ErrorHandler and AbortWithError.
+func (c *Context) Error(err error) *Error {
+	if err == nil {
+		panic("gee: err is nil")
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Err: err}
+	}
+	c.Errors = append(c.Errors, e)
+	return e
+}
+
+// Abort stops the remaining handlers of the chain from running
+func (c *Context) Abort() {
+	c.index = len(c.handlers)
+}
+
+// IsAborted reports whether the chain was stopped
+func (c *Context) IsAborted() bool {
+	return c.index >= len(c.handlers)
+}
+
+// AbortWithError stops the chain and attaches err with status code
+func (c *Context) AbortWithError(code int, err error) *Error {
+	c.Abort()
+	return c.Error(err).SetStatus(code)
+}
+
+// AbortWithProblem stops the chain and renders err as a public problem with
+// status code, for middlewares rejecting a request before the handler runs
+func (c *Context) AbortWithProblem(code int, err error) {
+	c.AbortWithError(code, err).SetType(ErrorTypePublic)
+	renderErrors(c)
+}
+
+// ErrorHandler renders the errors attached by the handlers after it,
+// unless they already started the response
+func ErrorHandler() HandlerFunc {
+	return func(c *Context) {
+		c.Next()
+		if len(c.Errors) > 0 && !c.Writer.Written() {
+			renderErrors(c)
+		}
+	}
+}
+
+// problem is an RFC 7807 problem details object
+type problem struct {
+	Type     string
+	Title    string
+	Status   int
+	Detail   string
+	Instance string
+	Errors   []string
+	Meta     H
+}
+
+func (p *problem) MarshalJSON() ([]byte, error) {
+	obj := H{}
+	for k, v := range p.Meta {
+		obj[k] = v
+	}
+	obj["type"] = p.Type
+	obj["title"] = p.Title
+	obj["status"] = p.Status
+	obj["instance"] = p.Instance
+	if p.Detail != "" {
+		obj["detail"] = p.Detail
+	}
+	if len(p.Errors) > 1 {
+		obj["errors"] = p.Errors
+	}
+	return json.Marshal(obj)
+}
+
+var problemTemplate = template.Must(template.New("problem").Parse(`<!DOCTYPE html>
+<html>
+<head><title>{{.Status}} {{.Title}}</title></head>
+<body>
+<h1>{{.Status}} {{.Title}}</h1>
+{{if .Detail}}<p>{{.Detail}}</p>
+{{end}}</body>
+</html>
+`))
+
+// renderErrors writes the errors of c as application/problem+json, HTML or
+// plain text depending on Accept. The last error decides the status, only
+// public errors are shown.
+func renderErrors(c *Context) {
+	last := c.Errors[len(c.Errors)-1]
+	p := &problem{Type: "about:blank", Status: last.Status, Instance: c.Path}
+	if p.Status == 0 {
+		p.Status = http.StatusInternalServerError
+	}
+	p.Title = http.StatusText(p.Status)
+	for _, e := range c.Errors {
+		if e.Type == ErrorTypePublic {
+			p.Errors = append(p.Errors, e.Error())
+			p.Detail = e.Error()
+			for k, v := range e.Meta {
+				if p.Meta == nil {
+					p.Meta = H{}
+				}
+				p.Meta[k] = v
+			}
+		}
+	}
+
+	c.Abort()
+	switch negotiateFormat(c.Req.Header.Get("Accept"), "application/problem+json", "application/json", "text/html", "text/plain") {
+	case "text/html":
+		c.SetHeader("Content-Type", "text/html; charset=utf-8")
+		c.Status(p.Status)
+		problemTemplate.Execute(c.Writer, p)
+	case "text/plain":
+		c.SetHeader("Content-Type", "text/plain; charset=utf-8")
+		c.Status(p.Status)
+		if p.Detail != "" {
+			fmt.Fprintf(c.Writer, "%d %s: %s\n", p.Status, p.Title, p.Detail)
+		} else {
+			fmt.Fprintf(c.Writer, "%d %s\n", p.Status, p.Title)
+		}
+	default:
+		c.SetHeader("Content-Type", "application/problem+json")
+		c.Status(p.Status)
+		json.NewEncoder(c.Writer).Encode(p)
+	}
+}
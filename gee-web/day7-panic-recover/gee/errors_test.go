@@ -0,0 +1,83 @@
+package gee
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorHandler(t *testing.T) {
+	r := New()
+	r.Use(ErrorHandler())
+	r.GET("/users/:id", func(c *Context) {
+		c.Error(errors.New("db timeout"))
+		c.AbortWithError(http.StatusNotFound, errors.New("user not found")).
+			SetType(ErrorTypePublic).
+			SetMeta("id", c.Param("id"))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/users/42", nil))
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	var p map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &p)
+	if p["title"] != "Not Found" || p["detail"] != "user not found" || p["id"] != "42" || p["instance"] != "/users/42" {
+		t.Fatalf("unexpected problem %s", w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "db timeout") {
+		t.Fatal("private errors shouldn't be rendered")
+	}
+
+	for accept, want := range map[string]string{
+		"text/html,application/xhtml+xml;q=0.9": "<h1>404 Not Found</h1>",
+		"text/plain, application/json;q=0.5":    "404 Not Found: user not found\n",
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/users/42", nil)
+		req.Header.Set("Accept", accept)
+		r.ServeHTTP(w, req)
+		if !strings.Contains(w.Body.String(), want) {
+			t.Fatalf("Accept %s should render %q, got %q", accept, want, w.Body.String())
+		}
+	}
+}
+
+func TestRecoveryProblem(t *testing.T) {
+	r := New()
+	r.Use(Recovery())
+	r.GET("/panic", func(c *Context) {
+		panic("secret internals")
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("panic should render a 500 problem, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "secret") {
+		t.Fatal("panic message shouldn't leak to clients")
+	}
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	cases := []struct {
+		accept string
+		offers []string
+		want   string
+	}{
+		{"", []string{"application/json", "text/html"}, "application/json"},
+		{"text/html;q=0.8, application/json", []string{"text/html", "application/json"}, "application/json"},
+		{"text/*;q=0.5, */*;q=0.1", []string{"application/json", "text/plain"}, "text/plain"},
+		{"image/png", []string{"application/json"}, ""},
+		{"application/json;q=0", []string{"application/json"}, ""},
+	}
+	for _, c := range cases {
+		if got := negotiateFormat(c.accept, c.offers...); got != c.want {
+			t.Fatalf("negotiateFormat(%q, %v) should be %q, got %q", c.accept, c.offers, c.want, got)
+		}
+	}
+}
@@ -1,11 +1,11 @@
 package gee
 
 import (
+	"fmt"
 	"html/template"
-	"log"
+	"net"
 	"net/http"
-	"path"
-	"strings"
+	"sync"
 )
 
 // HandlerFunc defines the request handler used by gee
@@ -18,14 +18,31 @@ type (
 		middlewares []HandlerFunc // support middleware
 		parent      *RouterGroup  // support nesting
 		engine      *Engine       // all groups share a Engine instance
+		host        *hostRouter   // routes of the host pattern, nil for any host
 	}
 
 	Engine struct {
 		*RouterGroup
-		router        *router
-		groups        []*RouterGroup     // store all groups
-		htmlTemplates *template.Template // for html render
-		funcMap       template.FuncMap   // for html render
+		router             *router
+		groups             []*RouterGroup          // store all groups
+		templates          map[string]*templateSet // named sets for html render, "" is the default
+		templatesMu        sync.RWMutex
+		funcMap            template.FuncMap // for html render
+		noRoute            []HandlerFunc    // user handlers for unmatched paths
+		noMethod           []HandlerFunc    // user handlers for paths matched by other methods
+		allNoRoute         []HandlerFunc    // global middlewares + noRoute
+		allNoMethod        []HandlerFunc    // global middlewares + noMethod
+		allOptions         []HandlerFunc    // global middlewares + automatic OPTIONS response
+		pool               sync.Pool        // recycles Context objects
+		config             ServerConfig     // settings of server
+		server             *http.Server     // shared by every Run* call
+		serverMu           sync.Mutex
+		namedRoutes        map[string]string      // route name to pattern, for URL
+		maxBodyBytes       int64                  // request body limit, 0 for none
+		maxMultipartMemory int64                  // memory used to parse multipart forms
+		trustedProxies     []*net.IPNet           // proxies whose forwarding headers are trusted
+		hosts              *node                  // trie of the host patterns, as paths of labels
+		hostRouters        map[string]*hostRouter // host path to its routes
 	}
 )
 
@@ -34,6 +51,10 @@ func New() *Engine {
 	engine := &Engine{router: newRouter()}
 	engine.RouterGroup = &RouterGroup{engine: engine}
 	engine.groups = []*RouterGroup{engine.RouterGroup}
+	engine.pool.New = func() interface{} {
+		return &Context{engine: engine, Params: make(Params, 0, engine.maxParams())}
+	}
+	engine.rebuildHandlers()
 	return engine
 }
 
@@ -52,79 +73,166 @@ func (group *RouterGroup) Group(prefix string) *RouterGroup {
 		prefix: group.prefix + prefix,
 		parent: group,
 		engine: engine,
+		host:   group.host,
 	}
 	engine.groups = append(engine.groups, newGroup)
 	return newGroup
 }
 
 // Use is defined to add middleware to the group
+// middlewares only apply to routes registered after Use is called
 func (group *RouterGroup) Use(middlewares ...HandlerFunc) {
 	group.middlewares = append(group.middlewares, middlewares...)
 }
 
-func (group *RouterGroup) addRoute(method string, comp string, handler HandlerFunc) {
+// Use adds global middlewares, they also wrap the NoRoute and NoMethod chains
+func (engine *Engine) Use(middlewares ...HandlerFunc) {
+	engine.RouterGroup.Use(middlewares...)
+	engine.rebuildHandlers()
+}
+
+// NoRoute sets the handlers run when no route matches the path, default is a plain 404
+func (engine *Engine) NoRoute(handlers ...HandlerFunc) {
+	engine.noRoute = handlers
+	engine.rebuildHandlers()
+}
+
+// NoMethod sets the handlers run when the path only matches routes of other methods,
+// default is a plain 405. The Allow header is already set when they run.
+func (engine *Engine) NoMethod(handlers ...HandlerFunc) {
+	engine.noMethod = handlers
+	engine.rebuildHandlers()
+}
+
+func (engine *Engine) rebuildHandlers() {
+	noRoute, noMethod := engine.noRoute, engine.noMethod
+	if len(noRoute) == 0 {
+		noRoute = []HandlerFunc{func(c *Context) {
+			c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+		}}
+	}
+	if len(noMethod) == 0 {
+		noMethod = []HandlerFunc{func(c *Context) {
+			c.String(http.StatusMethodNotAllowed, "405 METHOD NOT ALLOWED: %s\n", c.Method)
+		}}
+	}
+	engine.allNoRoute = engine.combineHandlers(noRoute)
+	engine.allNoMethod = engine.combineHandlers(noMethod)
+	engine.allOptions = engine.combineHandlers([]HandlerFunc{func(c *Context) {
+		c.Status(http.StatusNoContent)
+	}})
+}
+
+// combineHandlers returns a new chain of the engine, parent groups and group
+// middlewares, followed by handlers
+func (group *RouterGroup) combineHandlers(handlers []HandlerFunc) []HandlerFunc {
+	var groups []*RouterGroup
+	size := len(handlers)
+	for g := group; g != nil; g = g.parent {
+		groups = append(groups, g)
+		size += len(g.middlewares)
+	}
+	merged := make([]HandlerFunc, 0, size)
+	for i := len(groups) - 1; i >= 0; i-- {
+		merged = append(merged, groups[i].middlewares...)
+	}
+	return append(merged, handlers...)
+}
+
+func (group *RouterGroup) addRoute(method string, comp string, handlers []HandlerFunc) *Route {
+	if len(handlers) == 0 {
+		panic("gee: there must be at least one handler for route " + group.prefix + comp)
+	}
 	pattern := group.prefix + comp
-	log.Printf("Route %4s - %s", method, pattern)
-	group.engine.router.addRoute(method, pattern, handler)
+	r := group.engine.router
+	if group.host != nil {
+		r = group.host.router
+	}
+	n := r.addRoute(method, pattern, group.combineHandlers(handlers))
+	return &Route{Method: method, Pattern: pattern, engine: group.engine, nodes: []*node{n}}
 }
 
 // GET defines the method to add GET request
-func (group *RouterGroup) GET(pattern string, handler HandlerFunc) {
-	group.addRoute("GET", pattern, handler)
+func (group *RouterGroup) GET(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("GET", pattern, handlers)
 }
 
 // POST defines the method to add POST request
-func (group *RouterGroup) POST(pattern string, handler HandlerFunc) {
-	group.addRoute("POST", pattern, handler)
-}
-
-// create static handler
-func (group *RouterGroup) createStaticHandler(relativePath string, fs http.FileSystem) HandlerFunc {
-	absolutePath := path.Join(group.prefix, relativePath)
-	fileServer := http.StripPrefix(absolutePath, http.FileServer(fs))
-	return func(c *Context) {
-		file := c.Param("filepath")
-		// Check if file exists and/or if we have permission to access it
-		if _, err := fs.Open(file); err != nil {
-			c.Status(http.StatusNotFound)
-			return
-		}
+func (group *RouterGroup) POST(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("POST", pattern, handlers)
+}
 
-		fileServer.ServeHTTP(c.Writer, c.Req)
-	}
+// PUT defines the method to add PUT request
+func (group *RouterGroup) PUT(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("PUT", pattern, handlers)
+}
+
+// DELETE defines the method to add DELETE request
+func (group *RouterGroup) DELETE(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("DELETE", pattern, handlers)
+}
+
+// PATCH defines the method to add PATCH request
+func (group *RouterGroup) PATCH(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("PATCH", pattern, handlers)
 }
 
-// serve static files
-func (group *RouterGroup) Static(relativePath string, root string) {
-	handler := group.createStaticHandler(relativePath, http.Dir(root))
-	urlPattern := path.Join(relativePath, "/*filepath")
-	// Register GET handlers
-	group.GET(urlPattern, handler)
+// HEAD defines the method to add HEAD request
+func (group *RouterGroup) HEAD(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("HEAD", pattern, handlers)
+}
+
+// OPTIONS defines the method to add OPTIONS request
+// an explicit OPTIONS route overrides the automatic Allow response
+func (group *RouterGroup) OPTIONS(pattern string, handlers ...HandlerFunc) *Route {
+	return group.addRoute("OPTIONS", pattern, handlers)
+}
+
+// Any registers the handlers for all methods in anyMethods
+func (group *RouterGroup) Any(pattern string, handlers ...HandlerFunc) *Route {
+	route := &Route{Method: "ANY", Pattern: group.prefix + pattern, engine: group.engine}
+	for _, method := range anyMethods {
+		route.nodes = append(route.nodes, group.addRoute(method, pattern, handlers).nodes...)
+	}
+	return route
 }
 
-// for custom render function
+// SetFuncMap sets the funcs of the templates, loaded templates are parsed again
 func (engine *Engine) SetFuncMap(funcMap template.FuncMap) {
 	engine.funcMap = funcMap
+	engine.templatesMu.RLock()
+	defer engine.templatesMu.RUnlock()
+	for name, set := range engine.templates {
+		if err := set.load(engine.templateFuncs()); err != nil {
+			panic(fmt.Sprintf("gee: reload templates %q: %v", name, err))
+		}
+	}
 }
 
-func (engine *Engine) LoadHTMLGlob(pattern string) {
-	engine.htmlTemplates = template.Must(template.New("").Funcs(engine.funcMap).ParseGlob(pattern))
+// templateFuncs returns the default funcs, url for engine.URL, overridden by funcMap
+func (engine *Engine) templateFuncs() template.FuncMap {
+	funcs := template.FuncMap{
+		"url": engine.URL,
+	}
+	for name, fn := range engine.funcMap {
+		funcs[name] = fn
+	}
+	return funcs
 }
 
-// Run defines the method to start a http server
-func (engine *Engine) Run(addr string) (err error) {
-	return http.ListenAndServe(addr, engine)
+// LoadHTMLGlob parses the files matching pattern as the default template set,
+// see LoadHTMLTemplates. It panics on error.
+func (engine *Engine) LoadHTMLGlob(pattern string) {
+	if err := engine.LoadHTMLTemplates("", TemplateConfig{Pages: []string{pattern}}); err != nil {
+		panic(err)
+	}
 }
 
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	var middlewares []HandlerFunc
-	for _, group := range engine.groups {
-		if strings.HasPrefix(req.URL.Path, group.prefix) {
-			middlewares = append(middlewares, group.middlewares...)
-		}
-	}
-	c := newContext(w, req)
-	c.handlers = middlewares
-	c.engine = engine
-	engine.router.handle(c)
+	c := engine.pool.Get().(*Context)
+	c.reset(w, req)
+	c.limitBody()
+	engine.handle(c)
+	c.writermem.WriteHeaderNow()
+	engine.pool.Put(c)
 }
@@ -1,6 +1,11 @@
 package gee
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
 
 func TestNestedGroup(t *testing.T) {
 	r := New()
@@ -14,3 +19,67 @@ func TestNestedGroup(t *testing.T) {
 		t.Fatal("v2 prefix should be /v1/v2")
 	}
 }
+
+// traceMiddleware returns a middleware recording name into the X-Trace response header
+func traceMiddleware(name string) HandlerFunc {
+	return func(c *Context) {
+		c.Writer.Header().Add("X-Trace", name)
+		c.Next()
+	}
+}
+
+func TestMiddlewareChain(t *testing.T) {
+	r := New()
+	r.Use(traceMiddleware("engine"))
+	v1 := r.Group("/v1")
+	v1.Use(traceMiddleware("v1"))
+	admin := v1.Group("/admin")
+	admin.Use(traceMiddleware("admin"))
+	admin.GET("/users", traceMiddleware("route1"), traceMiddleware("route2"), func(c *Context) {
+		c.String(http.StatusOK, "users")
+	})
+	r.GET("/v10/users", func(c *Context) {
+		c.String(http.StatusOK, "v10")
+	})
+
+	cases := []struct {
+		path  string
+		trace string
+	}{
+		{"/v1/admin/users", "engine,v1,admin,route1,route2"},
+		{"/v10/users", "engine"},
+		{"/v1/unknown", "engine"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", c.path, nil))
+		if trace := strings.Join(w.Header()["X-Trace"], ","); trace != c.trace {
+			t.Fatalf("%s should run %q, got %q", c.path, c.trace, trace)
+		}
+	}
+}
+
+func TestNoRouteNoMethod(t *testing.T) {
+	r := New()
+	r.GET("/hello", func(c *Context) {})
+	r.NoRoute(func(c *Context) {
+		c.String(http.StatusNotFound, "custom 404")
+	})
+	r.NoMethod(func(c *Context) {
+		c.String(http.StatusMethodNotAllowed, "custom 405")
+	})
+	// global middlewares added later still wrap the NoRoute/NoMethod chains
+	r.Use(traceMiddleware("engine"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/nothing", nil))
+	if w.Code != http.StatusNotFound || w.Body.String() != "custom 404" || w.Header().Get("X-Trace") != "engine" {
+		t.Fatalf("unexpected NoRoute response %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/hello", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Body.String() != "custom 405" || w.Header().Get("Allow") == "" {
+		t.Fatalf("unexpected NoMethod response %d %q", w.Code, w.Body.String())
+	}
+}
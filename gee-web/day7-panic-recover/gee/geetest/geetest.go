@@ -0,0 +1,43 @@
+// Package geetest helps testing gee handlers and middlewares without a server.
+// Requests to a whole engine are sent with engine.Test().
+package geetest
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"gee"
+)
+
+// CreateTestContext returns a Context for a GET / request recording its
+// response in w, and the engine it belongs to
+func CreateTestContext(w http.ResponseWriter) (*gee.Context, *gee.Engine) {
+	engine := gee.New()
+	return gee.NewContextForTest(engine, w, httptest.NewRequest("GET", "/", nil)), engine
+}
+
+// MiddlewareResult is the outcome of running a middleware with RunMiddleware
+type MiddlewareResult struct {
+	// Context is the context the middleware ran with, it stays valid after the run
+	Context *gee.Context
+	// Recorder holds the response written by the middleware
+	Recorder *httptest.ResponseRecorder
+	// Reached reports whether the middleware called the next handler
+	Reached bool
+}
+
+// RunMiddleware runs middleware for req, followed by next if it is not nil,
+// and reports what it did
+func RunMiddleware(middleware gee.HandlerFunc, req *http.Request, next gee.HandlerFunc) *MiddlewareResult {
+	result := &MiddlewareResult{Recorder: httptest.NewRecorder()}
+	final := func(c *gee.Context) {
+		result.Reached = true
+		if next != nil {
+			next(c)
+		}
+	}
+	result.Context = gee.NewContextForTest(gee.New(), result.Recorder, req, middleware, final)
+	result.Context.Next()
+	result.Context.Writer.WriteHeaderNow()
+	return result
+}
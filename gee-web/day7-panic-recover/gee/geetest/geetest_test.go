@@ -0,0 +1,49 @@
+package geetest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gee"
+)
+
+func TestCreateTestContext(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, engine := CreateTestContext(w)
+	if engine == nil || c.Req.URL.Path != "/" {
+		t.Fatal("context should have a GET / request")
+	}
+	c.JSON(http.StatusCreated, gee.H{"name": "gee"})
+	if w.Code != http.StatusCreated || w.Body.String() != "{\"name\":\"gee\"}\n" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+}
+
+func auth(c *gee.Context) {
+	if c.Req.Header.Get("Authorization") != "Bearer token" {
+		c.AbortWithError(http.StatusUnauthorized, errors.New("unauthorized"))
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+	c.Set("user", "gee")
+	c.Next()
+}
+
+func TestRunMiddleware(t *testing.T) {
+	result := RunMiddleware(auth, httptest.NewRequest("GET", "/", nil), nil)
+	if result.Reached || !result.Context.IsAborted() || result.Recorder.Code != http.StatusUnauthorized ||
+		len(result.Context.Errors) != 1 {
+		t.Fatalf("auth should reject the request, got %+v", result)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	result = RunMiddleware(auth, req, func(c *gee.Context) {
+		c.String(http.StatusOK, c.MustGet("user").(string))
+	})
+	if !result.Reached || result.Context.MustGet("user") != "gee" || result.Recorder.Body.String() != "gee" {
+		t.Fatalf("auth should pass the request, got %+v", result)
+	}
+}
@@ -0,0 +1,117 @@
+package gee
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hostRouter holds the routes registered for a host pattern
+type hostRouter struct {
+	pattern string // host pattern, such as {tenant}.example.com
+	params  int    // number of params captured from the host
+	router  *router
+}
+
+// Host returns a group whose routes only match requests for the host pattern.
+// Its labels are literal, or params {name} and {name:constraint} captured for
+// c.Param, e.g. "{tenant}.example.com". The case and port of the request host
+// are ignored. Requests matching no route of their host fall back to the
+// routes of the engine, with the host params still set.
+func (engine *Engine) Host(pattern string) *RouterGroup {
+	path := hostPath(pattern)
+	hr, ok := engine.hostRouters[path]
+	if !ok {
+		if engine.hosts == nil {
+			engine.hosts = &node{}
+			engine.hostRouters = make(map[string]*hostRouter)
+		}
+		engine.hosts.insert(path, nil)
+		hr = &hostRouter{pattern: pattern, router: newRouter()}
+		for _, part := range parsePattern(path) {
+			if part[0] == '{' {
+				hr.params++
+			}
+		}
+		engine.hostRouters[path] = hr
+	}
+	group := &RouterGroup{
+		parent: engine.RouterGroup,
+		engine: engine,
+		host:   hr,
+	}
+	engine.groups = append(engine.groups, group)
+	return group
+}
+
+// hostPath turns a host pattern into a path of its labels for the trie:
+// "{tenant}.Example.com" becomes "/{tenant}/example/com". Constraints are
+// kept as is but can't contain dots, as a param matches a single label.
+func hostPath(pattern string) string {
+	if pattern == "" || strings.ContainsAny(pattern, "/*") {
+		panic(fmt.Sprintf("gee: invalid host pattern %q", pattern))
+	}
+	var b strings.Builder
+	b.WriteByte('/')
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		switch {
+		case ch == '{':
+			depth++
+		case ch == '}':
+			depth--
+		case depth > 0:
+			if ch == '.' {
+				panic(fmt.Sprintf("gee: constraint of host pattern %s can't contain '.', a param matches a single label", pattern))
+			}
+		case ch == '.':
+			ch = '/'
+		case ch == ':':
+			panic(fmt.Sprintf("gee: host pattern %s must not have a port", pattern))
+		case 'A' <= ch && ch <= 'Z':
+			ch += 'a' - 'A'
+		}
+		b.WriteByte(ch)
+	}
+	path := b.String()
+	if strings.Contains(path, "//") || strings.HasSuffix(path, "/") {
+		panic(fmt.Sprintf("gee: host pattern %s has an empty label", pattern))
+	}
+	validatePattern(path)
+	return path
+}
+
+// matchHost returns the routes of the host pattern matching the request,
+// appending the host params to c.Params
+func (engine *Engine) matchHost(c *Context) *router {
+	if engine.hosts == nil {
+		return nil
+	}
+	host := c.Req.Host
+	if i := strings.LastIndexByte(host, ':'); i > strings.LastIndexByte(host, ']') {
+		host = host[:i]
+	}
+	host = strings.TrimSuffix(host, ".")
+	if host == "" {
+		return nil
+	}
+	n := engine.hosts.search("/"+strings.Replace(strings.ToLower(host), ".", "/", -1), &c.Params)
+	if n == nil {
+		return nil
+	}
+	return engine.hostRouters[n.pattern].router
+}
+
+// maxParams returns the capacity a Params needs so that lookups never grow it
+func (engine *Engine) maxParams() int {
+	routes, host := engine.router.maxParams, 0
+	for _, hr := range engine.hostRouters {
+		if hr.router.maxParams > routes {
+			routes = hr.router.maxParams
+		}
+		if hr.params > host {
+			host = hr.params
+		}
+	}
+	return routes + host
+}
@@ -0,0 +1,105 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHost(t *testing.T) {
+	r := New()
+	r.Use(func(c *Context) {
+		c.SetHeader("X-Global", "1")
+		c.Next()
+	})
+	r.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "default %s", c.Param("tenant"))
+	})
+	r.GET("/healthz", func(c *Context) {
+		c.String(http.StatusOK, "ok %s", c.Param("tenant"))
+	})
+	api := r.Host("api.example.com")
+	api.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "api")
+	})
+	admin := r.Host("admin.example.com").Group("/admin")
+	admin.GET("/users/:id", func(c *Context) {
+		c.String(http.StatusOK, "admin %s", c.Param("id"))
+	})
+	tenants := r.Host("{tenant}.example.com")
+	tenants.GET("/", func(c *Context) {
+		c.String(http.StatusOK, "tenant %s", c.Param("tenant"))
+	})
+	tenants.POST("/users/{id:int}", func(c *Context) {
+		c.String(http.StatusOK, "tenant %s user %s", c.Param("tenant"), c.Param("id"))
+	})
+	r.Host("{id:[0-9]+}.{region}.example.com").GET("/", func(c *Context) {
+		c.String(http.StatusOK, "node %s %s", c.Param("id"), c.Param("region"))
+	})
+
+	cases := []struct {
+		method, host, path string
+		code               int
+		body               string
+	}{
+		{"GET", "api.example.com", "/", http.StatusOK, "api"},
+		{"GET", "API.Example.com:8080", "/", http.StatusOK, "api"},
+		{"GET", "admin.example.com", "/admin/users/7", http.StatusOK, "admin 7"},
+		{"GET", "acme.example.com.", "/", http.StatusOK, "tenant acme"},
+		{"POST", "acme.example.com", "/users/7", http.StatusOK, "tenant acme user 7"},
+		{"GET", "42.eu.example.com", "/", http.StatusOK, "node 42 eu"},
+		{"GET", "acme.example.com", "/healthz", http.StatusOK, "ok acme"},
+		{"GET", "example.com", "/", http.StatusOK, "default "},
+		{"GET", "a.b.example.com", "/", http.StatusOK, "default "},
+		{"GET", "other.org", "/admin/users/7", http.StatusNotFound, "404 NOT FOUND: /admin/users/7\n"},
+		{"GET", "acme.example.com", "/users/7", http.StatusMethodNotAllowed, "405 METHOD NOT ALLOWED: GET\n"},
+		{"POST", "api.example.com", "/users/7", http.StatusNotFound, "404 NOT FOUND: /users/7\n"},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, nil)
+		req.Host = c.host
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.code || w.Body.String() != c.body || w.Header().Get("X-Global") != "1" {
+			t.Fatalf("%s %s%s should be %d %q, got %d %q", c.method, c.host, c.path, c.code, c.body, w.Code, w.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest("OPTIONS", "/", nil)
+	req.Host = "acme.example.com"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") != "GET, OPTIONS" {
+		t.Fatalf("unexpected OPTIONS response %d %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	routes := r.Routes()
+	if len(routes) != 7 || routes[0].Host != "" || routes[2].Host != "admin.example.com" ||
+		routes[2].Pattern != "/admin/users/:id" {
+		t.Fatalf("unexpected routes %+v", routes)
+	}
+}
+
+func TestHostPatterns(t *testing.T) {
+	r := New()
+	if r.Host("api.example.com").host != r.Host("API.example.com").host {
+		t.Fatal("host patterns should ignore case")
+	}
+	for _, pattern := range []string{"", "example.com:8080", "a..example.com", "*.example.com", "example.com/x", "{a}.{b:[}.com", `{sub:[a-z]+\.example}.com`, "{h:.+}.com"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("host pattern %q should panic", pattern)
+				}
+			}()
+			New().Host(pattern)
+		}()
+	}
+	r.Host("{tenant}.example.com")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("conflicting host params should panic")
+		}
+	}()
+	r.Host("{org}.example.com")
+}
@@ -0,0 +1,243 @@
+package gee
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TemplateConfig describes the files of a template set, templates are
+// named by the base name of their file
+type TemplateConfig struct {
+	// FS holds the files, the OS file system when nil
+	FS fs.FS
+	// Layouts are glob patterns of the layouts and partials shared by the pages
+	Layouts []string
+	// Pages are glob patterns of the pages. Without Layouts they are parsed
+	// together. With Layouts every page is parsed with its own copy of them,
+	// so that its {{define}}s override the {{block}}s of the layout.
+	Pages []string
+	// Debug reparses the files when they change, checked on every render
+	Debug bool
+}
+
+// ErrTemplateNotFound is attached by c.HTML for unknown template names
+var ErrTemplateNotFound = errors.New("gee: template not found")
+
+// templateSet is a named set of templates loaded from TemplateConfig
+type templateSet struct {
+	config  TemplateConfig
+	mu      sync.RWMutex
+	funcs   template.FuncMap
+	pages   map[string]*template.Template // page name to the template executing it
+	modTime map[string]time.Time          // files loaded, for Debug
+}
+
+// LoadHTMLTemplates loads the template set name, "" is the default set used
+// by c.HTML. Routes use another set with RouterGroup.UseTemplates.
+func (engine *Engine) LoadHTMLTemplates(name string, config TemplateConfig) error {
+	set := &templateSet{config: config}
+	if err := set.load(engine.templateFuncs()); err != nil {
+		return err
+	}
+	engine.templatesMu.Lock()
+	defer engine.templatesMu.Unlock()
+	if engine.templates == nil {
+		engine.templates = make(map[string]*templateSet)
+	}
+	engine.templates[name] = set
+	return nil
+}
+
+// UseTemplates makes c.HTML use the template set name in the routes of the
+// group registered after it
+func (group *RouterGroup) UseTemplates(name string) {
+	group.Use(func(c *Context) {
+		c.templateSet = name
+		c.Next()
+	})
+}
+
+func (set *templateSet) glob(pattern string) ([]string, error) {
+	if set.config.FS != nil {
+		return fs.Glob(set.config.FS, pattern)
+	}
+	return filepath.Glob(pattern)
+}
+
+func (set *templateSet) stat(name string) (fs.FileInfo, error) {
+	if set.config.FS != nil {
+		return fs.Stat(set.config.FS, name)
+	}
+	return os.Stat(name)
+}
+
+func (set *templateSet) readFile(name string) ([]byte, error) {
+	if set.config.FS != nil {
+		return fs.ReadFile(set.config.FS, name)
+	}
+	return os.ReadFile(name)
+}
+
+// files returns the files matched by patterns, sorted
+func (set *templateSet) files(patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := set.glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("gee: pattern %q matches no files", pattern)
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// parse parses files into t, each as a template named by its base name
+func (set *templateSet) parse(t *template.Template, files []string, modTime map[string]time.Time) error {
+	for _, file := range files {
+		b, err := set.readFile(file)
+		if err != nil {
+			return err
+		}
+		if info, err := set.stat(file); err == nil {
+			modTime[file] = info.ModTime()
+		}
+		if _, err := t.New(path.Base(filepath.ToSlash(file))).Parse(string(b)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load parses the files of the set with funcs, the set is unchanged on error
+func (set *templateSet) load(funcs template.FuncMap) error {
+	layouts, err := set.files(set.config.Layouts)
+	if err != nil {
+		return err
+	}
+	pageFiles, err := set.files(set.config.Pages)
+	if err != nil {
+		return err
+	}
+
+	modTime := make(map[string]time.Time)
+	pages := make(map[string]*template.Template)
+	root := template.New("").Funcs(funcs)
+	if len(layouts) == 0 {
+		if err := set.parse(root, pageFiles, modTime); err != nil {
+			return err
+		}
+		for _, t := range root.Templates() {
+			pages[t.Name()] = root
+		}
+	} else {
+		if err := set.parse(root, layouts, modTime); err != nil {
+			return err
+		}
+		for _, file := range pageFiles {
+			name := path.Base(filepath.ToSlash(file))
+			if _, ok := pages[name]; ok {
+				return fmt.Errorf("gee: duplicate page %q", name)
+			}
+			page, err := root.Clone()
+			if err != nil {
+				return err
+			}
+			if err := set.parse(page, []string{file}, modTime); err != nil {
+				return err
+			}
+			pages[name] = page
+		}
+	}
+
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	set.funcs, set.pages, set.modTime = funcs, pages, modTime
+	return nil
+}
+
+// changed reports whether files were added, removed or modified since load
+func (set *templateSet) changed() bool {
+	set.mu.RLock()
+	defer set.mu.RUnlock()
+	files, err := set.files(append(append([]string(nil), set.config.Layouts...), set.config.Pages...))
+	if err != nil || len(files) != len(set.modTime) {
+		return true
+	}
+	for _, file := range files {
+		info, err := set.stat(file)
+		loaded, ok := set.modTime[file]
+		if err != nil || !ok || !info.ModTime().Equal(loaded) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the template executing page name
+func (set *templateSet) lookup(name string) (*template.Template, error) {
+	if set.config.Debug && set.changed() {
+		if err := set.load(set.funcs); err != nil {
+			return nil, err
+		}
+	}
+	set.mu.RLock()
+	defer set.mu.RUnlock()
+	t, ok := set.pages[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrTemplateNotFound, name)
+	}
+	return t, nil
+}
+
+func (engine *Engine) templateSet(name string) (*templateSet, error) {
+	engine.templatesMu.RLock()
+	defer engine.templatesMu.RUnlock()
+	set, ok := engine.templates[name]
+	if !ok {
+		if name == "" {
+			return nil, errors.New("gee: no HTML templates loaded")
+		}
+		return nil, fmt.Errorf("gee: template set %q not loaded", name)
+	}
+	return set, nil
+}
+
+// HTML renders the template name of the route's template set with data.
+// The page is rendered before anything is sent, so that a template error
+// becomes a 500 problem, detailed when the set is in Debug mode.
+// refer https://golang.org/pkg/html/template/
+func (c *Context) HTML(code int, name string, data interface{}) {
+	var buf bytes.Buffer
+	set, err := c.engine.templateSet(c.templateSet)
+	if err == nil {
+		var t *template.Template
+		if t, err = set.lookup(name); err == nil {
+			err = t.ExecuteTemplate(&buf, name, data)
+		}
+	}
+	if err != nil {
+		e := c.AbortWithError(http.StatusInternalServerError, err)
+		if set != nil && set.config.Debug {
+			e.SetType(ErrorTypePublic)
+		}
+		if !c.Writer.Written() {
+			renderErrors(c)
+		}
+		return
+	}
+	c.Render(code, Data{ContentType: "text/html; charset=utf-8", Data: buf.Bytes()})
+}
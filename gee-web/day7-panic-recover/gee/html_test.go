@@ -0,0 +1,137 @@
+package gee
+
+import (
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var templateFS = fstest.MapFS{
+	"layouts/base.html":     {Data: []byte(`<title>{{block "title" .}}gee{{end}}</title>{{template "nav.html" .}}<main>{{block "content" .}}{{end}}</main>`)},
+	"layouts/nav.html":      {Data: []byte(`<nav>{{upper "home"}}</nav>`)},
+	"pages/users.html":      {Data: []byte(`{{template "base.html" .}}{{define "title"}}users{{end}}{{define "content"}}{{range .}}<p>{{.}}</p>{{end}}{{end}}`)},
+	"pages/about.html":      {Data: []byte(`{{template "base.html" .}}{{define "content"}}about{{end}}`)},
+	"pages/broken.html":     {Data: []byte(`{{template "base.html" .}}{{define "content"}}{{.Missing.Field}}{{end}}`)},
+	"admin/dashboard.html":  {Data: []byte(`admin {{.}}`)},
+	"admin/partials/x.html": {Data: []byte(`x`)},
+}
+
+func htmlRequest(r *Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	return w
+}
+
+func TestHTMLLayouts(t *testing.T) {
+	r := New()
+	r.SetFuncMap(template.FuncMap{"upper": strings.ToUpper})
+	err := r.LoadHTMLTemplates("", TemplateConfig{FS: templateFS, Layouts: []string{"layouts/*.html"}, Pages: []string{"pages/*.html"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.LoadHTMLTemplates("admin", TemplateConfig{FS: templateFS, Pages: []string{"admin/*.html"}}); err != nil {
+		t.Fatal(err)
+	}
+	r.GET("/users", func(c *Context) { c.HTML(http.StatusOK, "users.html", []string{"a", "b"}) })
+	r.GET("/about", func(c *Context) { c.HTML(http.StatusOK, "about.html", nil) })
+	r.GET("/broken", func(c *Context) { c.HTML(http.StatusOK, "broken.html", 1) })
+	r.GET("/missing", func(c *Context) { c.HTML(http.StatusOK, "missing.html", nil) })
+	admin := r.Group("/admin")
+	admin.UseTemplates("admin")
+	admin.GET("/", func(c *Context) { c.HTML(http.StatusOK, "dashboard.html", "ok") })
+
+	w := htmlRequest(r, "/users")
+	if w.Code != http.StatusOK || w.Body.String() != `<title>users</title><nav>HOME</nav><main><p>a</p><p>b</p></main>` ||
+		w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("unexpected page %d %q", w.Code, w.Body.String())
+	}
+	if w := htmlRequest(r, "/about"); w.Body.String() != `<title>gee</title><nav>HOME</nav><main>about</main>` {
+		t.Fatalf("pages should only override their own blocks, got %q", w.Body.String())
+	}
+	if w := htmlRequest(r, "/admin/"); w.Body.String() != "admin ok" {
+		t.Fatalf("group should use its template set, got %q", w.Body.String())
+	}
+
+	for _, path := range []string{"/broken", "/missing"} {
+		w := htmlRequest(r, path)
+		if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "<title>") {
+			t.Fatalf("%s should be a clean 500, got %d %q", path, w.Code, w.Body.String())
+		}
+	}
+
+	r.SetFuncMap(template.FuncMap{"upper": strings.ToLower})
+	if w := htmlRequest(r, "/about"); !strings.Contains(w.Body.String(), "<nav>home</nav>") {
+		t.Fatalf("SetFuncMap should apply to loaded templates, got %q", w.Body.String())
+	}
+}
+
+func TestHTMLNotLoaded(t *testing.T) {
+	r := New()
+	r.GET("/", func(c *Context) { c.HTML(http.StatusOK, "index.html", nil) })
+	if w := htmlRequest(r, "/"); w.Code != http.StatusInternalServerError {
+		t.Fatalf("missing templates should be a 500, got %d", w.Code)
+	}
+	if err := r.LoadHTMLTemplates("", TemplateConfig{FS: templateFS, Pages: []string{"nothing/*.html"}}); err == nil {
+		t.Fatal("patterns matching nothing should fail")
+	}
+	if err := r.LoadHTMLTemplates("", TemplateConfig{FS: templateFS, Layouts: []string{"layouts/*.html"}, Pages: []string{"admin/*.html", "pages/about.html", "pages/about.html"}}); err == nil {
+		t.Fatal("duplicate pages should fail")
+	}
+}
+
+func TestHTMLDebugReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "index.html")
+	ioutil.WriteFile(file, []byte("v1 {{.}}"), 0644)
+
+	r := New()
+	if err := r.LoadHTMLTemplates("", TemplateConfig{Pages: []string{filepath.Join(dir, "*.html")}, Debug: true}); err != nil {
+		t.Fatal(err)
+	}
+	r.GET("/", func(c *Context) { c.HTML(http.StatusOK, "index.html", "gee") })
+	if w := htmlRequest(r, "/"); w.Body.String() != "v1 gee" {
+		t.Fatalf("unexpected page %q", w.Body.String())
+	}
+
+	ioutil.WriteFile(file, []byte("v2 {{.}}"), 0644)
+	later := time.Now().Add(time.Second)
+	os.Chtimes(file, later, later)
+	if w := htmlRequest(r, "/"); w.Body.String() != "v2 gee" {
+		t.Fatalf("debug mode should reparse changed files, got %q", w.Body.String())
+	}
+
+	ioutil.WriteFile(file, []byte("v3 {{.Broken"), 0644)
+	later = later.Add(time.Second)
+	os.Chtimes(file, later, later)
+	if w := htmlRequest(r, "/"); w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "unclosed action") {
+		t.Fatalf("debug mode should show template errors, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestLoadHTMLGlob(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "a.tmpl"), []byte(`a {{template "b.tmpl" .}}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "b.tmpl"), []byte(`b {{.}}`), 0644)
+
+	r := New()
+	r.LoadHTMLGlob(filepath.Join(dir, "*.tmpl"))
+	r.GET("/", func(c *Context) { c.HTML(http.StatusOK, "a.tmpl", "gee") })
+	if w := htmlRequest(r, "/"); w.Body.String() != "a b gee" {
+		t.Fatalf("globbed templates should see each other, got %q", w.Body.String())
+	}
+}
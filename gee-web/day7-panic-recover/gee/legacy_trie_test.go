@@ -0,0 +1,119 @@
+package gee
+
+import "strings"
+
+// legacyNode is the segment trie gee used before the radix tree,
+// kept only so the benchmarks can compare against it
+type legacyNode struct {
+	pattern  string
+	part     string
+	children []*legacyNode
+	isWild   bool
+}
+
+func (n *legacyNode) insert(pattern string, parts []string, height int) {
+	if len(parts) == height {
+		n.pattern = pattern
+		return
+	}
+
+	part := parts[height]
+	child := n.matchChild(part)
+	if child == nil {
+		child = &legacyNode{part: part, isWild: part[0] == ':' || part[0] == '*'}
+		n.children = append(n.children, child)
+	}
+	child.insert(pattern, parts, height+1)
+}
+
+func (n *legacyNode) search(parts []string, height int) *legacyNode {
+	if len(parts) == height || strings.HasPrefix(n.part, "*") {
+		if n.pattern == "" {
+			return nil
+		}
+		return n
+	}
+
+	part := parts[height]
+	for _, child := range n.matchChildren(part) {
+		result := child.search(parts, height+1)
+		if result != nil {
+			return result
+		}
+	}
+	return nil
+}
+
+func (n *legacyNode) matchChild(part string) *legacyNode {
+	for _, child := range n.children {
+		if child.part == part {
+			return child
+		}
+	}
+	return nil
+}
+
+func (n *legacyNode) matchChildren(part string) []*legacyNode {
+	nodes := make([]*legacyNode, 0)
+	for _, child := range n.children {
+		if !child.isWild && child.part == part {
+			nodes = append(nodes, child)
+		}
+	}
+	for _, child := range n.children {
+		if child.isWild {
+			nodes = append(nodes, child)
+		}
+	}
+	return nodes
+}
+
+type legacyRouter struct {
+	roots    map[string]*legacyNode
+	handlers map[string]HandlerFunc
+}
+
+func newLegacyRouter() *legacyRouter {
+	return &legacyRouter{
+		roots:    make(map[string]*legacyNode),
+		handlers: make(map[string]HandlerFunc),
+	}
+}
+
+func (r *legacyRouter) addRoute(method string, pattern string, handler HandlerFunc) {
+	parts := parsePattern(pattern)
+
+	key := method + "-" + pattern
+	_, ok := r.roots[method]
+	if !ok {
+		r.roots[method] = &legacyNode{}
+	}
+	r.roots[method].insert(pattern, parts, 0)
+	r.handlers[key] = handler
+}
+
+func (r *legacyRouter) getRoute(method string, path string) (HandlerFunc, map[string]string) {
+	searchParts := parsePattern(path)
+	params := make(map[string]string)
+	root, ok := r.roots[method]
+	if !ok {
+		return nil, nil
+	}
+
+	n := root.search(searchParts, 0)
+	if n == nil {
+		return nil, nil
+	}
+
+	parts := parsePattern(n.pattern)
+	for index, part := range parts {
+		if part[0] == ':' {
+			params[part[1:]] = searchParts[index]
+		}
+		if part[0] == '*' && len(part) > 1 {
+			params[part[1:]] = strings.Join(searchParts[index:], "/")
+			break
+		}
+	}
+	return r.handlers[method+"-"+n.pattern], params
+}
@@ -1,17 +1,118 @@
 package gee
 
 import (
-	"log"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"os"
 	"time"
 )
 
+// LogFormat is the output format of the access log
+type LogFormat int
+
+const (
+	// LogFormatText writes logfmt key=value lines
+	LogFormatText LogFormat = iota
+	// LogFormatJSON writes a JSON object per line
+	LogFormatJSON
+)
+
+// LoggerConfig configures the access log written by LoggerWithConfig
+type LoggerConfig struct {
+	// Handler receives the records, when nil one is made from Format and Output
+	Handler slog.Handler
+	Format  LogFormat
+	Output  io.Writer // os.Stderr if nil
+	// SkipPaths are request paths never logged, such as health checks
+	SkipPaths []string
+	// SampleRate is the fraction of requests below 500 that are logged,
+	// 0 logs every request
+	SampleRate float64
+}
+
+// Logger logs every request in logfmt to os.Stderr
 func Logger() HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{})
+}
+
+// LoggerWithConfig logs one "access" record per request with its method, path,
+// route pattern, status, latency, bytes in and out, client IP, user agent and
+// the id set by RequestID
+func LoggerWithConfig(config LoggerConfig) HandlerFunc {
+	handler := config.Handler
+	if handler == nil {
+		out := config.Output
+		if out == nil {
+			out = os.Stderr
+		}
+		if config.Format == LogFormatJSON {
+			handler = slog.NewJSONHandler(out, nil)
+		} else {
+			handler = slog.NewTextHandler(out, nil)
+		}
+	}
+	logger := slog.New(handler)
+	skip := make(map[string]bool, len(config.SkipPaths))
+	for _, path := range config.SkipPaths {
+		skip[path] = true
+	}
+
 	return func(c *Context) {
+		if skip[c.Path] {
+			c.Next()
+			return
+		}
 		// Start timer
 		t := time.Now()
+		var body *countingBody
+		if c.Req.Body != nil && c.Req.Body != http.NoBody {
+			body = &countingBody{ReadCloser: c.Req.Body}
+			c.Req.Body = body
+		}
 		// Process request
 		c.Next()
-		// Calculate resolution time
-		log.Printf("[%d] %s in %v", c.StatusCode, c.Req.RequestURI, time.Since(t))
+
+		status := c.Writer.Status()
+		if config.SampleRate > 0 && status < 500 && rand.Float64() >= config.SampleRate {
+			return
+		}
+		var bytesIn int64
+		if body != nil {
+			bytesIn = body.n
+		}
+		bytesOut := c.Writer.Size()
+		if bytesOut < 0 {
+			bytesOut = 0
+		}
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(c.Req.Context(), level, "access",
+			slog.String("method", c.Method),
+			slog.String("path", c.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(t)),
+			slog.Int64("bytes_in", bytesIn),
+			slog.Int("bytes_out", bytesOut),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Req.UserAgent()),
+			slog.String("request_id", c.RequestID()),
+		)
 	}
 }
+
+// countingBody counts the bytes of the request body read by the handlers
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,97 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.Use(RequestID(), LoggerWithConfig(LoggerConfig{Format: LogFormatJSON, Output: &buf, SkipPaths: []string{"/healthz"}}))
+	r.POST("/users/:id", func(c *Context) {
+		var obj H
+		c.ShouldBindJSON(&obj)
+		c.String(http.StatusCreated, "created")
+	})
+	r.GET("/healthz", func(c *Context) {})
+
+	req := httptest.NewRequest("POST", "/users/42", strings.NewReader(`{"name":"gee"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "geetest")
+	req.Header.Set(RequestIDHeader, "req-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get(RequestIDHeader) != "req-1" {
+		t.Fatalf("request id should be propagated, got %q", w.Header().Get(RequestIDHeader))
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("access log should be one JSON record, got %s", buf.String())
+	}
+	for k, v := range map[string]interface{}{
+		"msg":        "access",
+		"method":     "POST",
+		"path":       "/users/42",
+		"route":      "/users/:id",
+		"status":     float64(201),
+		"bytes_in":   float64(14),
+		"bytes_out":  float64(7),
+		"client_ip":  "192.0.2.1",
+		"user_agent": "geetest",
+		"request_id": "req-1",
+	} {
+		if record[k] != v {
+			t.Fatalf("%s should be %v, got %v", k, v, record[k])
+		}
+	}
+
+	buf.Reset()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/healthz", nil))
+	if buf.Len() != 0 {
+		t.Fatalf("skipped paths shouldn't be logged, got %s", buf.String())
+	}
+}
+
+func TestLoggerText(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.Use(LoggerWithConfig(LoggerConfig{Output: &buf, SampleRate: 0.000001}))
+	r.GET("/ok", func(c *Context) {})
+	r.GET("/fail", func(c *Context) { c.Status(http.StatusInternalServerError) })
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	if buf.Len() != 0 {
+		t.Fatalf("sampled out requests shouldn't be logged, got %s", buf.String())
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	if !strings.Contains(buf.String(), "level=ERROR msg=access method=GET path=/fail route=/fail status=500") {
+		t.Fatalf("server errors should always be logged, got %s", buf.String())
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	r := New()
+	r.Use(RequestID())
+	r.GET("/", func(c *Context) { c.String(http.StatusOK, c.RequestID()) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	id := w.Header().Get(RequestIDHeader)
+	if len(id) != 32 || w.Body.String() != id {
+		t.Fatalf("request id should be generated, got %q %q", id, w.Body.String())
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, "bad\nid")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if id := w.Header().Get(RequestIDHeader); id == "bad\nid" || len(id) != 32 {
+		t.Fatalf("invalid request id should be replaced, got %q", id)
+	}
+}
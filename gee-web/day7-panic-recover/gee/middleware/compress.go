@@ -0,0 +1,336 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gee"
+)
+
+// ErrInvalidGzipBody is attached when a gzip request body can't be decompressed
+var ErrInvalidGzipBody = errors.New("middleware: invalid gzip request body")
+
+// CompressConfig configures the Compress middleware
+type CompressConfig struct {
+	// Level is the gzip/zlib compression level
+	Level int
+	// MinLength is the smallest body compressed, flushed responses are
+	// compressed whatever their length
+	MinLength int
+	// ExcludedContentTypes are Content-Type prefixes of already compressed data
+	ExcludedContentTypes []string
+	// ExcludedPaths are request paths never compressed, a trailing "*" matches a prefix
+	ExcludedPaths []string
+	// MaxDecompressedBytes limits the size of gzip request bodies once
+	// decompressed, as the engine body limit only counts compressed bytes.
+	// The smaller of it and the engine limit applies, 0 means no limit.
+	MaxDecompressedBytes int64
+}
+
+// DefaultCompressConfig compresses bodies of 1 KiB or more, except images,
+// audio, video and archives
+func DefaultCompressConfig() CompressConfig {
+	return CompressConfig{
+		Level:                gzip.DefaultCompression,
+		MinLength:            1024,
+		MaxDecompressedBytes: 32 << 20,
+		ExcludedContentTypes: []string{
+			"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+			"audio/", "video/", "font/woff",
+			"application/zip", "application/gzip", "application/x-gzip",
+			"application/zstd", "application/x-7z-compressed", "application/x-rar-compressed",
+			"application/pdf", "application/octet-stream",
+		},
+	}
+}
+
+// Compress compresses responses with gzip or deflate as negotiated by
+// Accept-Encoding, and decompresses request bodies sent with
+// Content-Encoding: gzip. Bodies are buffered until MinLength bytes or a
+// Flush, so small responses, HEAD requests, partial content and bodies that
+// already have a Content-Encoding are sent as is. Reading a decompressed body
+// past its limit fails with *http.MaxBytesError, and the request is answered
+// with 413 unless the handler already responded.
+func Compress(config CompressConfig) gee.HandlerFunc {
+	if config.Level < gzip.HuffmanOnly || config.Level > gzip.BestCompression {
+		panic("middleware: invalid compression level " + strconv.Itoa(config.Level))
+	}
+	var gzipPool, zlibPool sync.Pool
+	gzipPool.New = func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, config.Level)
+		return w
+	}
+	zlibPool.New = func() interface{} {
+		w, _ := zlib.NewWriterLevel(nil, config.Level)
+		return w
+	}
+
+	return func(c *gee.Context) {
+		var body *gzipBody
+		if strings.EqualFold(c.Req.Header.Get("Content-Encoding"), "gzip") && c.Req.Body != nil {
+			limit := config.MaxDecompressedBytes
+			if max := c.MaxBodyBytes(); max > 0 && (limit <= 0 || max < limit) {
+				limit = max
+			}
+			var err error
+			if body, err = decompressBody(c.Req, limit); err != nil {
+				c.AbortWithProblem(http.StatusBadRequest, ErrInvalidGzipBody)
+				return
+			}
+		}
+		// next runs the handlers, answering 413 for a body over the limit
+		next := func() {
+			c.Next()
+			if body != nil && body.tooLarge && !c.Writer.Written() {
+				c.AbortWithProblem(http.StatusRequestEntityTooLarge, gee.ErrBodyTooLarge)
+			}
+		}
+		if matchPaths(config.ExcludedPaths, c.Path) {
+			next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.Req.Header.Get("Accept-Encoding"))
+		if encoding == "" || c.Method == "HEAD" {
+			next()
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: c.Writer, config: &config, encoding: encoding}
+		switch encoding {
+		case "gzip":
+			cw.newEncoder = func(w io.Writer) encoder {
+				gz := gzipPool.Get().(*gzip.Writer)
+				gz.Reset(w)
+				return gz
+			}
+			cw.release = func(e encoder) { gzipPool.Put(e) }
+		case "deflate":
+			cw.newEncoder = func(w io.Writer) encoder {
+				zw := zlibPool.Get().(*zlib.Writer)
+				zw.Reset(w)
+				return zw
+			}
+			cw.release = func(e encoder) { zlibPool.Put(e) }
+		}
+
+		c.Writer = cw
+		defer func() {
+			cw.finish()
+			c.Writer = cw.ResponseWriter
+		}()
+		next()
+	}
+}
+
+// gzipBody is a decompressed request body
+type gzipBody struct {
+	reader   io.ReadCloser // the gzip reader, capped when there is a limit
+	body     io.Closer
+	tooLarge bool // the limit was hit
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		b.tooLarge = true
+	}
+	return n, err
+}
+
+func (b *gzipBody) Close() error {
+	b.reader.Close()
+	return b.body.Close()
+}
+
+// decompressBody replaces the body of req with its decompressed content,
+// limited to limit bytes when it is positive
+func decompressBody(req *http.Request, limit int64) (*gzipBody, error) {
+	zr, err := gzip.NewReader(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	body := &gzipBody{reader: zr, body: req.Body}
+	if limit > 0 {
+		body.reader = http.MaxBytesReader(nil, zr, limit)
+	}
+	req.Body = body
+	req.Header.Del("Content-Encoding")
+	req.Header.Del("Content-Length")
+	req.ContentLength = -1
+	return body, nil
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header,
+// preferring gzip on equal quality, empty if neither is acceptable
+func negotiateEncoding(accept string) string {
+	q := map[string]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		name, params := part, ""
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			name, params = part[:i], part[i+1:]
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		quality := 1.0
+		params = strings.TrimSpace(params)
+		if strings.HasPrefix(params, "q=") {
+			if v, err := strconv.ParseFloat(params[2:], 64); err == nil {
+				quality = v
+			}
+		}
+		q[name] = quality
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range []string{"gzip", "deflate"} {
+		quality, ok := q[encoding]
+		if !ok {
+			quality = q["*"]
+		}
+		if quality > bestQ {
+			best, bestQ = encoding, quality
+		}
+	}
+	return best
+}
+
+func matchPaths(paths []string, path string) bool {
+	for _, p := range paths {
+		if strings.HasSuffix(p, "*") {
+			if strings.HasPrefix(path, p[:len(p)-1]) {
+				return true
+			}
+		} else if path == p {
+			return true
+		}
+	}
+	return false
+}
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// compressWriter buffers the start of the body to decide whether it is worth
+// compressing, then writes it through an encoder or as is
+type compressWriter struct {
+	gee.ResponseWriter
+	config     *CompressConfig
+	encoding   string
+	newEncoder func(w io.Writer) encoder
+	release    func(e encoder)
+
+	buf     []byte
+	decided bool
+	enc     encoder // nil when the body is sent as is
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.config.MinLength {
+			return len(data), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// decide starts the response, compressed if compress is set and the
+// response allows it, then writes the buffered body
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if compress && w.compressible() {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges")
+		w.enc = w.newEncoder(w.ResponseWriter)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.enc != nil {
+		_, err := w.enc.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func (w *compressWriter) compressible() bool {
+	header := w.Header()
+	status := w.Status()
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified ||
+		status == http.StatusPartialContent || header.Get("Content-Range") != "" ||
+		header.Get("Content-Encoding") != "" {
+		return false
+	}
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	for _, excluded := range w.config.ExcludedContentTypes {
+		if strings.HasPrefix(contentType, excluded) {
+			return false
+		}
+	}
+	return true
+}
+
+// Written also reports a body that is still buffered
+func (w *compressWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// WriteHeaderNow starts the response, which is then never compressed
+// if nothing was written before
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.decide(len(w.buf) > 0)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Flush compresses what was written so far, however short, and sends it
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(len(w.buf) > 0)
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// finish writes what is left of the body when the handlers are done
+func (w *compressWriter) finish() {
+	if !w.decided {
+		w.decide(len(w.buf) >= w.config.MinLength)
+	}
+	if w.enc != nil {
+		w.enc.Close()
+		w.release(w.enc)
+		w.enc = nil
+	}
+}
@@ -0,0 +1,220 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gee"
+)
+
+func compressEngine() *gee.Engine {
+	r := gee.New()
+	r.Use(Compress(DefaultCompressConfig()))
+	big := func(c *gee.Context) {
+		c.JSON(http.StatusOK, gee.H{"data": strings.Repeat("gee ", 1000)})
+	}
+	r.GET("/big", big)
+	r.HEAD("/big", big)
+	r.GET("/small", func(c *gee.Context) { c.String(http.StatusOK, "small") })
+	r.GET("/png", func(c *gee.Context) {
+		c.SetHeader("Content-Type", "image/png")
+		c.Data(http.StatusOK, bytes.Repeat([]byte{1}, 2048))
+	})
+	r.GET("/stream", func(c *gee.Context) {
+		for i := 0; i < 3; i++ {
+			c.SSEvent("tick", i)
+		}
+	})
+	r.POST("/echo", func(c *gee.Context) {
+		b, _ := ioutil.ReadAll(c.Req.Body)
+		c.String(http.StatusOK, "%s", b)
+	})
+	return r
+}
+
+func get(r *gee.Engine, method, path, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if accept != "" {
+		req.Header.Set("Accept-Encoding", accept)
+	}
+	return serve(r, req)
+}
+
+func TestCompress(t *testing.T) {
+	r := compressEngine()
+
+	w := get(r, "GET", "/big", "deflate;q=0.5, gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" ||
+		w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected headers %v", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(zr)
+	if !strings.Contains(string(body), "gee gee") || w.Body.Len() > len(body)/4 {
+		t.Fatalf("body should be compressed, %d bytes for %d", w.Body.Len(), len(body))
+	}
+
+	w = get(r, "GET", "/big", "deflate, gzip;q=0.1")
+	if w.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("deflate should be preferred, got %v", w.Header())
+	}
+	zlr, err := zlib.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := ioutil.ReadAll(zlr); !strings.Contains(string(body), "gee gee") {
+		t.Fatal("deflate body should be readable")
+	}
+
+	for _, tc := range []struct{ method, path, accept string }{
+		{"GET", "/big", ""},
+		{"GET", "/big", "gzip;q=0, br"},
+		{"GET", "/small", "gzip"},
+		{"GET", "/png", "gzip"},
+		{"HEAD", "/big", "gzip"},
+	} {
+		w := get(r, tc.method, tc.path, tc.accept)
+		if w.Header().Get("Content-Encoding") != "" || w.Code != http.StatusOK {
+			t.Fatalf("%+v shouldn't be compressed, got %d %v", tc, w.Code, w.Header())
+		}
+	}
+	if w := get(r, "GET", "/small", "gzip"); w.Body.String() != "small" {
+		t.Fatalf("small body should be sent as is, got %q", w.Body.String())
+	}
+}
+
+func TestCompressStream(t *testing.T) {
+	w := get(compressEngine(), "GET", "/stream", "gzip")
+	if !w.Flushed || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("stream should be flushed compressed, got %v", w.Header())
+	}
+	zr, _ := gzip.NewReader(w.Body)
+	body, _ := ioutil.ReadAll(zr)
+	if !strings.Contains(string(body), "event: tick\ndata: 2\n\n") {
+		t.Fatalf("unexpected stream %q", body)
+	}
+}
+
+func TestCompressStatic(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	content := strings.Repeat("body { color: red; }\n", 200)
+	ioutil.WriteFile(filepath.Join(dir, "main.css"), []byte(content), 0644)
+
+	r := gee.New()
+	r.Use(Compress(DefaultCompressConfig()))
+	r.Static("/assets", dir)
+
+	w := get(r, "GET", "/assets/main.css", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Content-Length") != "" {
+		t.Fatalf("static file should be compressed, got %v", w.Header())
+	}
+	zr, _ := gzip.NewReader(w.Body)
+	if body, _ := ioutil.ReadAll(zr); string(body) != content {
+		t.Fatal("static file should round trip")
+	}
+
+	req := httptest.NewRequest("GET", "/assets/main.css", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-9")
+	if w := serve(r, req); w.Code != http.StatusPartialContent || w.Header().Get("Content-Encoding") != "" || w.Body.Len() != 10 {
+		t.Fatalf("partial content shouldn't be compressed, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestDecompressRequest(t *testing.T) {
+	r := compressEngine()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	io.WriteString(zw, "hello gee")
+	zw.Close()
+
+	req := httptest.NewRequest("POST", "/echo", &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	if w := serve(r, req); w.Body.String() != "hello gee" {
+		t.Fatalf("gzip body should be decompressed, got %q", w.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/echo", strings.NewReader("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	if w := serve(r, req); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid gzip body should be rejected, got %d", w.Code)
+	}
+}
+
+func TestDecompressLimit(t *testing.T) {
+	gzipped := func(n int) *bytes.Buffer {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(make([]byte, n))
+		zw.Close()
+		return &buf
+	}
+	newEngine := func(config CompressConfig) *gee.Engine {
+		r := gee.New()
+		r.Use(Compress(config))
+		r.POST("/read", func(c *gee.Context) {
+			if b, err := io.ReadAll(c.Req.Body); err == nil {
+				c.String(http.StatusOK, "%d", len(b))
+			}
+		})
+		return r
+	}
+
+	// the engine limit applies to the decompressed body
+	r := newEngine(CompressConfig{})
+	r.SetMaxBodyBytes(64 << 10)
+	body := gzipped(8 << 20)
+	if body.Len() > 64<<10 {
+		t.Fatalf("compressed body should fit the engine limit, got %d bytes", body.Len())
+	}
+	req := httptest.NewRequest("POST", "/read", body)
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Accept-Encoding", "gzip")
+	if w := serve(r, req); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("decompressed body over the engine limit should be 413, got %d %q", w.Code, w.Body.String())
+	}
+
+	r = newEngine(CompressConfig{MaxDecompressedBytes: 1 << 10})
+	req = httptest.NewRequest("POST", "/read", gzipped(1<<10))
+	req.Header.Set("Content-Encoding", "gzip")
+	if w := serve(r, req); w.Code != http.StatusOK || w.Body.String() != "1024" {
+		t.Fatalf("body at the limit should be read, got %d %q", w.Code, w.Body.String())
+	}
+	req = httptest.NewRequest("POST", "/read", gzipped(1<<10+1))
+	req.Header.Set("Content-Encoding", "gzip")
+	if w := serve(r, req); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("body over MaxDecompressedBytes should be 413, got %d", w.Code)
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	for accept, want := range map[string]string{
+		"":                          "",
+		"gzip, deflate, br":         "gzip",
+		"deflate":                   "deflate",
+		"*":                         "gzip",
+		"*;q=0.5, gzip;q=0.1":       "deflate",
+		"identity":                  "",
+		"GZIP;q=0.8, deflate;q=0.9": "deflate",
+	} {
+		if got := negotiateEncoding(accept); got != want {
+			t.Fatalf("negotiateEncoding(%q) should be %q, got %q", accept, want, got)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Package middleware provides the standard middlewares of gee services,
+// they are installed with Engine.Use or RouterGroup.Use
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gee"
+)
+
+// CORSConfig configures the CORS middleware
+type CORSConfig struct {
+	// AllowOrigins lists the allowed origins, "*" allows any origin and a
+	// single "*" in an origin matches any subdomain, as in "https://*.example.com"
+	AllowOrigins []string
+	// AllowOriginFunc is consulted when no entry of AllowOrigins matches
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods are the methods allowed by preflight requests
+	AllowMethods []string
+	// AllowHeaders are the request headers allowed by preflight requests,
+	// empty allows the headers the client asks for
+	AllowHeaders []string
+	// ExposeHeaders are the response headers readable by the client
+	ExposeHeaders []string
+	// AllowCredentials lets requests carry cookies and auth headers, it
+	// can't be combined with the "*" origin since any site could then make
+	// authenticated requests
+	AllowCredentials bool
+	// MaxAge is how long the client may cache preflight results
+	MaxAge time.Duration
+}
+
+// DefaultCORSConfig allows simple and preflighted requests from any origin
+func DefaultCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowOrigins: []string{"*"},
+		AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"},
+		MaxAge:       12 * time.Hour,
+	}
+}
+
+// ErrOriginNotAllowed is attached when a preflight request comes from a disallowed origin
+var ErrOriginNotAllowed = errors.New("middleware: origin not allowed")
+
+// CORS answers preflight requests and adds the Access-Control-* headers to the
+// responses for allowed origins. The engine answers OPTIONS requests of routes
+// lacking an OPTIONS handler with the engine middlewares only, so install it with
+// Engine.Use, or register OPTIONS routes in the group using it.
+// It panics when AllowCredentials is set with the "*" origin.
+func CORS(config CORSConfig) gee.HandlerFunc {
+	if config.AllowCredentials {
+		for _, origin := range config.AllowOrigins {
+			if origin == "*" {
+				panic(`middleware: CORS can't allow credentials from the "*" origin, list the allowed origins`)
+			}
+		}
+	}
+	allowMethods := strings.Join(config.AllowMethods, ", ")
+	allowHeaders := strings.Join(config.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(config.ExposeHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge / time.Second))
+
+	return func(c *gee.Context) {
+		origin := c.Req.Header.Get("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
+		preflight := c.Method == "OPTIONS" && c.Req.Header.Get("Access-Control-Request-Method") != ""
+
+		allowed, wildcard := config.allowOrigin(origin)
+		if !allowed {
+			if preflight {
+				c.AbortWithProblem(http.StatusForbidden, ErrOriginNotAllowed)
+				return
+			}
+			c.Next()
+			return
+		}
+		if wildcard {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if config.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			c.Next()
+			return
+		}
+
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		if allowMethods != "" {
+			header.Set("Access-Control-Allow-Methods", allowMethods)
+		}
+		if allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+		} else if requested := c.Req.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			header.Set("Access-Control-Allow-Headers", requested)
+		}
+		if config.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.Status(http.StatusNoContent)
+		c.Abort()
+	}
+}
+
+// allowOrigin reports whether origin is allowed, and whether it is through "*"
+func (config *CORSConfig) allowOrigin(origin string) (allowed, wildcard bool) {
+	for _, pattern := range config.AllowOrigins {
+		if pattern == "*" {
+			return true, true
+		}
+		if matchOrigin(pattern, origin) {
+			return true, false
+		}
+	}
+	if config.AllowOriginFunc != nil {
+		return config.AllowOriginFunc(origin), false
+	}
+	return false, false
+}
+
+// matchOrigin matches origin against pattern, case-insensitively, where a "*"
+// stands for one or more characters
+func matchOrigin(pattern, origin string) bool {
+	pattern, origin = strings.ToLower(pattern), strings.ToLower(origin)
+	i := strings.IndexByte(pattern, '*')
+	if i < 0 {
+		return pattern == origin
+	}
+	prefix, suffix := pattern[:i], pattern[i+1:]
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
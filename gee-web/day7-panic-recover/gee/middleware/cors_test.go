@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gee"
+)
+
+func serve(r *gee.Engine, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS(t *testing.T) {
+	r := gee.New()
+	r.Use(CORS(CORSConfig{
+		AllowOrigins:     []string{"https://*.example.com", "http://localhost:8080"},
+		AllowMethods:     []string{"GET", "PUT"},
+		ExposeHeaders:    []string{"X-Total"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}))
+	r.GET("/users", func(c *gee.Context) { c.String(http.StatusOK, "users") })
+	r.PUT("/users", func(c *gee.Context) { c.String(http.StatusOK, "updated") })
+
+	req := httptest.NewRequest("OPTIONS", "/users", nil)
+	req.Header.Set("Origin", "https://api.example.com")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+	w := serve(r, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("preflight should be answered with 204, got %d", w.Code)
+	}
+	for k, v := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://api.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Allow-Methods":     "GET, PUT",
+		"Access-Control-Allow-Headers":     "Content-Type",
+		"Access-Control-Max-Age":           "3600",
+	} {
+		if got := w.Header().Get(k); got != v {
+			t.Fatalf("%s should be %q, got %q", k, v, got)
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/users", nil)
+	req.Header.Set("Origin", "http://localhost:8080")
+	w = serve(r, req)
+	if w.Body.String() != "users" || w.Header().Get("Access-Control-Allow-Origin") != "http://localhost:8080" ||
+		w.Header().Get("Access-Control-Expose-Headers") != "X-Total" {
+		t.Fatalf("unexpected simple response %s %v", w.Body.String(), w.Header())
+	}
+
+	req = httptest.NewRequest("OPTIONS", "/users", nil)
+	req.Header.Set("Origin", "https://example.com.evil.org")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	if w := serve(r, req); w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("preflight from a disallowed origin should fail, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/users", nil)
+	req.Header.Set("Origin", "https://evil.org")
+	if w := serve(r, req); w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatal("disallowed origins shouldn't get CORS headers")
+	}
+}
+
+func TestCORSWildcard(t *testing.T) {
+	r := gee.New()
+	r.Use(CORS(DefaultCORSConfig()))
+	r.GET("/", func(c *gee.Context) {})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Origin", "https://any.org")
+	if w := serve(r, req); w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Fatalf("any origin should be allowed with *, got %v", w.Header())
+	}
+}
+
+func TestCORSWildcardCredentials(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("credentials with the * origin should panic")
+		}
+	}()
+	config := DefaultCORSConfig()
+	config.AllowCredentials = true
+	CORS(config)
+}
+
+func TestMatchOrigin(t *testing.T) {
+	cases := []struct {
+		pattern, origin string
+		match           bool
+	}{
+		{"https://example.com", "https://EXAMPLE.com", true},
+		{"https://*.example.com", "https://a.b.example.com", true},
+		{"https://*.example.com", "https://.example.com", false},
+		{"https://*.example.com", "http://a.example.com", false},
+		{"https://*.example.com", "https://example.com", false},
+	}
+	for _, c := range cases {
+		if matchOrigin(c.pattern, c.origin) != c.match {
+			t.Fatalf("matchOrigin(%s, %s) should be %v", c.pattern, c.origin, c.match)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+
+	"gee"
+)
+
+const (
+	// csrfKey is the context key of the token, templates receiving c.Keys can use {{.csrf}}
+	csrfKey = "csrf"
+	// csrfFieldKey is the context key of the FormField of the config
+	csrfFieldKey = "middleware/csrf-field"
+)
+
+// ErrCSRFToken is attached when an unsafe request lacks a valid CSRF token
+var ErrCSRFToken = errors.New("middleware: missing or invalid CSRF token")
+
+// CSRFConfig configures the CSRF middleware
+type CSRFConfig struct {
+	// CookieName is the cookie holding the token, _csrf when empty
+	CookieName string
+	// HeaderName is the request header carrying the token, checked first,
+	// X-CSRF-Token when empty
+	HeaderName string
+	// FormField is the form field carrying the token, the token is only read
+	// from HeaderName when empty
+	FormField string
+	// Exempt lists the paths that are not checked, a trailing "*" matches a prefix
+	Exempt []string
+	// ExemptFunc skips the check when it returns true
+	ExemptFunc func(c *gee.Context) bool
+
+	// attributes of the token cookie, it is readable by scripts unless
+	// CookieHTTPOnly is set so that they can copy it into HeaderName.
+	// CookiePath is / when empty.
+	CookiePath     string
+	CookieDomain   string
+	CookieMaxAge   int
+	CookieSecure   bool
+	CookieHTTPOnly bool
+	CookieSameSite http.SameSite
+}
+
+// DefaultCSRFConfig uses the _csrf cookie and form field and the X-CSRF-Token header
+func DefaultCSRFConfig() CSRFConfig {
+	return CSRFConfig{
+		CookieName:     "_csrf",
+		HeaderName:     "X-CSRF-Token",
+		FormField:      "_csrf",
+		CookiePath:     "/",
+		CookieMaxAge:   86400,
+		CookieSameSite: http.SameSiteLaxMode,
+	}
+}
+
+// CSRF protects against cross-site request forgery with double-submit tokens.
+// Every client gets a random token cookie, requests with a method other than
+// GET, HEAD, OPTIONS and TRACE must send the same token in the header or form
+// field, or they are rejected with 403. The token is available to handlers
+// through CSRFToken and CSRFField.
+func CSRF(config CSRFConfig) gee.HandlerFunc {
+	defaults := DefaultCSRFConfig()
+	if config.CookieName == "" {
+		config.CookieName = defaults.CookieName
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = defaults.HeaderName
+	}
+	if config.CookiePath == "" {
+		config.CookiePath = defaults.CookiePath
+	}
+	return func(c *gee.Context) {
+		token := ""
+		if cookie, err := c.Req.Cookie(config.CookieName); err == nil && validToken(cookie.Value) {
+			token = cookie.Value
+		}
+
+		switch {
+		case isSafeMethod(c.Method), config.exempt(c):
+		default:
+			sent := c.Req.Header.Get(config.HeaderName)
+			if sent == "" && config.FormField != "" {
+				sent = c.Req.PostFormValue(config.FormField)
+			}
+			if token == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+				c.AbortWithProblem(http.StatusForbidden, ErrCSRFToken)
+				return
+			}
+		}
+
+		if token == "" {
+			var err error
+			if token, err = newToken(); err != nil {
+				c.AbortWithError(http.StatusInternalServerError, err)
+				return
+			}
+			http.SetCookie(c.Writer, &http.Cookie{
+				Name:     config.CookieName,
+				Value:    token,
+				Path:     config.CookiePath,
+				Domain:   config.CookieDomain,
+				MaxAge:   config.CookieMaxAge,
+				Secure:   config.CookieSecure,
+				HttpOnly: config.CookieHTTPOnly,
+				SameSite: config.CookieSameSite,
+			})
+		}
+		c.Set(csrfKey, token)
+		c.Set(csrfFieldKey, config.FormField)
+		c.Next()
+	}
+}
+
+// CSRFToken returns the token of the request, empty without the CSRF middleware
+func CSRFToken(c *gee.Context) string {
+	token, _ := c.Get(csrfKey)
+	s, _ := token.(string)
+	return s
+}
+
+// CSRFField returns a hidden input named by the FormField of the config
+// carrying the token, for use in HTML forms. It is empty without the CSRF
+// middleware or when the config has no FormField.
+func CSRFField(c *gee.Context) template.HTML {
+	name, _ := c.Get(csrfFieldKey)
+	field, _ := name.(string)
+	if field == "" {
+		return ""
+	}
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(field) +
+		`" value="` + template.HTMLEscapeString(CSRFToken(c)) + `">`)
+}
+
+func isSafeMethod(method string) bool {
+	return method == "GET" || method == "HEAD" || method == "OPTIONS" || method == "TRACE"
+}
+
+func (config *CSRFConfig) exempt(c *gee.Context) bool {
+	for _, path := range config.Exempt {
+		if strings.HasSuffix(path, "*") {
+			if strings.HasPrefix(c.Path, path[:len(path)-1]) {
+				return true
+			}
+		} else if c.Path == path {
+			return true
+		}
+	}
+	return config.ExemptFunc != nil && config.ExemptFunc(c)
+}
+
+const tokenLength = 32
+
+func newToken() (string, error) {
+	b := make([]byte, tokenLength)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func validToken(token string) bool {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	return err == nil && len(b) == tokenLength
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"gee"
+)
+
+func TestCSRF(t *testing.T) {
+	config := DefaultCSRFConfig()
+	config.Exempt = []string{"/webhooks/*"}
+	r := gee.New()
+	r.Use(CSRF(config))
+	r.GET("/form", func(c *gee.Context) { c.String(http.StatusOK, "%s", CSRFField(c)) })
+	r.POST("/form", func(c *gee.Context) { c.String(http.StatusOK, "ok") })
+	r.POST("/webhooks/github", func(c *gee.Context) { c.String(http.StatusOK, "hook") })
+
+	w := serve(r, httptest.NewRequest("GET", "/form", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "_csrf" {
+		t.Fatalf("GET should issue a token cookie, got %v", cookies)
+	}
+	token := cookies[0].Value
+	if !strings.Contains(w.Body.String(), `value="`+token+`"`) {
+		t.Fatalf("token should be available to templates, got %s", w.Body.String())
+	}
+
+	post := func(token, header string, cookie bool) int {
+		form := url.Values{"_csrf": {token}}
+		req := httptest.NewRequest("POST", "/form", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if header != "" {
+			req.Header.Set("X-CSRF-Token", header)
+		}
+		if cookie {
+			req.AddCookie(cookies[0])
+		}
+		return serve(r, req).Code
+	}
+	if code := post(token, "", true); code != http.StatusOK {
+		t.Fatalf("form token should pass, got %d", code)
+	}
+	if code := post("", token, true); code != http.StatusOK {
+		t.Fatalf("header token should pass, got %d", code)
+	}
+	if code := post("forged", "", true); code != http.StatusForbidden {
+		t.Fatalf("wrong token should be rejected, got %d", code)
+	}
+	if code := post(token, "", false); code != http.StatusForbidden {
+		t.Fatalf("token without cookie should be rejected, got %d", code)
+	}
+
+	if w := serve(r, httptest.NewRequest("POST", "/webhooks/github", nil)); w.Code != http.StatusOK {
+		t.Fatalf("exempt routes shouldn't be checked, got %d", w.Code)
+	}
+}
+
+func TestCSRFFieldName(t *testing.T) {
+	config := DefaultCSRFConfig()
+	config.FormField = "authenticity_token"
+	r := gee.New()
+	r.Use(CSRF(config))
+	r.GET("/form", func(c *gee.Context) { c.String(http.StatusOK, "%s", CSRFField(c)) })
+	r.POST("/form", func(c *gee.Context) { c.String(http.StatusOK, "ok") })
+
+	w := serve(r, httptest.NewRequest("GET", "/form", nil))
+	cookie := w.Result().Cookies()[0]
+	if want := `name="authenticity_token" value="` + cookie.Value + `"`; !strings.Contains(w.Body.String(), want) {
+		t.Fatalf("field should be named by the config, got %s", w.Body.String())
+	}
+
+	form := url.Values{"authenticity_token": {cookie.Value}}
+	req := httptest.NewRequest("POST", "/form", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	if w := serve(r, req); w.Code != http.StatusOK {
+		t.Fatalf("renamed form field should pass, got %d", w.Code)
+	}
+}
+
+func TestCSRFEmptyConfig(t *testing.T) {
+	r := gee.New()
+	r.Use(CSRF(CSRFConfig{}))
+	r.GET("/form", func(c *gee.Context) { c.String(http.StatusOK, "%s", CSRFField(c)) })
+	r.POST("/form", func(c *gee.Context) { c.String(http.StatusOK, "ok") })
+
+	w := serve(r, httptest.NewRequest("GET", "/form", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "_csrf" || cookies[0].Path != "/" {
+		t.Fatalf("an empty config should issue the default cookie, got %v", cookies)
+	}
+	if w.Body.Len() != 0 {
+		t.Fatalf("no field should be rendered without FormField, got %s", w.Body.String())
+	}
+
+	req := httptest.NewRequest("POST", "/form", nil)
+	req.Header.Set("X-CSRF-Token", cookies[0].Value)
+	req.AddCookie(cookies[0])
+	if w := serve(r, req); w.Code != http.StatusOK {
+		t.Fatalf("the default header should carry the token, got %d", w.Code)
+	}
+}
@@ -0,0 +1,261 @@
+package middleware
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"gee"
+)
+
+// ErrRateLimited is attached when a request exceeds its limit
+var ErrRateLimited = errors.New("middleware: rate limit exceeded")
+
+// RateResult is the outcome of taking one request from a limit
+type RateResult struct {
+	Allowed    bool
+	Limit      int           // requests allowed in a full window or burst
+	Remaining  int           // requests left right now
+	Reset      time.Time     // when the limit is fully available again
+	RetryAfter time.Duration // wait before the next request is allowed, 0 if allowed
+}
+
+// Store keeps the rate limit state of every key, implementations backed by
+// a shared database let instances enforce a common limit
+type Store interface {
+	// Take counts one request of key at now
+	Take(key string, now time.Time) (RateResult, error)
+}
+
+// RateLimitConfig configures the RateLimit middleware
+type RateLimitConfig struct {
+	// Store holds the limits, see NewTokenBucket and NewSlidingWindow
+	Store Store
+	// KeyFunc returns the key requests are limited by, KeyByIP if nil.
+	// An empty key skips the limit.
+	KeyFunc func(c *gee.Context) string
+}
+
+// KeyByIP limits requests by c.ClientIP()
+func KeyByIP(c *gee.Context) string {
+	return c.ClientIP()
+}
+
+// KeyByHeader limits requests by the value of the header name, such as an API key
+func KeyByHeader(name string) func(c *gee.Context) string {
+	return func(c *gee.Context) string {
+		return c.Req.Header.Get(name)
+	}
+}
+
+// RateLimit rejects requests over the limit of their key with 429. Every limited
+// response carries X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset
+// (unix seconds), rejected ones also Retry-After. Store failures are attached
+// with c.Error and let the request through.
+func RateLimit(config RateLimitConfig) gee.HandlerFunc {
+	if config.Store == nil {
+		panic("middleware: RateLimit requires a Store")
+	}
+	keyFunc := config.KeyFunc
+	if keyFunc == nil {
+		keyFunc = KeyByIP
+	}
+
+	return func(c *gee.Context) {
+		key := keyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+		result, err := config.Store.Take(key, time.Now())
+		if err != nil {
+			c.Error(err)
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("X-RateLimit-Reset", strconv.FormatInt(result.Reset.Unix(), 10))
+		if !result.Allowed {
+			header.Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+			c.AbortWithProblem(http.StatusTooManyRequests, ErrRateLimited)
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateEntries is the in-memory state shared by the built-in stores. Entries idle
+// for longer than idle are evicted, at most once per idle period.
+type rateEntries struct {
+	mu        sync.Mutex
+	entries   map[string]interface{}
+	seen      map[string]time.Time
+	idle      time.Duration
+	lastSweep time.Time
+}
+
+func newRateEntries(idle time.Duration) rateEntries {
+	return rateEntries{entries: make(map[string]interface{}), seen: make(map[string]time.Time), idle: idle}
+}
+
+// touchLocked marks key as seen at now and sweeps idle keys, the caller must hold mu
+func (r *rateEntries) touchLocked(key string, now time.Time) {
+	r.seen[key] = now
+	if now.Sub(r.lastSweep) < r.idle {
+		return
+	}
+	r.lastSweep = now
+	for k, t := range r.seen {
+		if now.Sub(t) > r.idle {
+			delete(r.seen, k)
+			delete(r.entries, k)
+		}
+	}
+}
+
+// Len returns the number of keys tracked
+func (r *rateEntries) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucket allows bursts of Burst requests, refilled at Rate requests per second
+type TokenBucket struct {
+	Rate  float64
+	Burst int
+	rateEntries
+}
+
+var _ Store = &TokenBucket{}
+
+// NewTokenBucket returns an in-memory token bucket store. Buckets are evicted
+// once idle for the time they take to refill, as they are full again by then.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if rate <= 0 || burst <= 0 {
+		panic("middleware: token bucket rate and burst must be positive")
+	}
+	fill := time.Duration(float64(burst) / rate * float64(time.Second))
+	return &TokenBucket{Rate: rate, Burst: burst, rateEntries: newRateEntries(fill)}
+}
+
+func (tb *TokenBucket) Take(key string, now time.Time) (RateResult, error) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.touchLocked(key, now)
+
+	b, ok := tb.entries[key].(*bucket)
+	if !ok {
+		b = &bucket{tokens: float64(tb.Burst), last: now}
+		tb.entries[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(tb.Burst), b.tokens+elapsed*tb.Rate)
+		b.last = now
+	}
+
+	result := RateResult{Limit: tb.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = tb.seconds(1 - b.tokens)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = now.Add(tb.seconds(float64(tb.Burst) - b.tokens))
+	return result, nil
+}
+
+// seconds returns the time refilling tokens takes
+func (tb *TokenBucket) seconds(tokens float64) time.Duration {
+	return time.Duration(tokens / tb.Rate * float64(time.Second))
+}
+
+type window struct {
+	start    time.Time
+	count    int
+	previous int
+}
+
+// SlidingWindow allows Limit requests in any Window, estimating the count of
+// the sliding window from the counts of the current and previous fixed windows
+type SlidingWindow struct {
+	Limit  int
+	Window time.Duration
+	rateEntries
+}
+
+var _ Store = &SlidingWindow{}
+
+// NewSlidingWindow returns an in-memory sliding window store,
+// counters are evicted once idle for two windows
+func NewSlidingWindow(limit int, size time.Duration) *SlidingWindow {
+	if limit <= 0 || size <= 0 {
+		panic("middleware: sliding window limit and size must be positive")
+	}
+	return &SlidingWindow{Limit: limit, Window: size, rateEntries: newRateEntries(2 * size)}
+}
+
+func (sw *SlidingWindow) Take(key string, now time.Time) (RateResult, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	sw.touchLocked(key, now)
+
+	start := now.Truncate(sw.Window)
+	w, ok := sw.entries[key].(*window)
+	if !ok {
+		w = &window{start: start}
+		sw.entries[key] = w
+	}
+	switch {
+	case start.Sub(w.start) >= 2*sw.Window:
+		w.start, w.count, w.previous = start, 0, 0
+	case start.After(w.start):
+		w.start, w.count, w.previous = start, 0, w.count
+	}
+
+	elapsed := now.Sub(w.start)
+	weight := 1 - float64(elapsed)/float64(sw.Window)
+	estimate := float64(w.previous)*weight + float64(w.count)
+
+	result := RateResult{Limit: sw.Limit}
+	if estimate+1 <= float64(sw.Limit) {
+		w.count++
+		estimate++
+		result.Allowed = true
+	} else {
+		result.RetryAfter = sw.retryAfter(w, elapsed)
+	}
+	result.Remaining = int(math.Max(0, float64(sw.Limit)-estimate))
+	// the previous window stops counting at the end of the current one,
+	// which counts until the end of the next one
+	result.Reset = w.start.Add(sw.Window)
+	if w.count > 0 {
+		result.Reset = result.Reset.Add(sw.Window)
+	}
+	return result, nil
+}
+
+// retryAfter returns how long until one more request fits in the window
+func (sw *SlidingWindow) retryAfter(w *window, elapsed time.Duration) time.Duration {
+	free := float64(sw.Limit - 1 - w.count)
+	if free >= 0 {
+		// the current window has room once the previous one weighs less
+		at := float64(sw.Window) * (1 - free/float64(w.previous))
+		return time.Duration(at) - elapsed
+	}
+	// wait for the next window, where the current one becomes the previous
+	at := float64(sw.Window) * (1 - float64(sw.Limit-1)/float64(w.count))
+	return sw.Window - elapsed + time.Duration(at)
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gee"
+)
+
+func TestTokenBucket(t *testing.T) {
+	tb := NewTokenBucket(1, 3)
+	now := time.Unix(1000, 0)
+	for i := 2; i >= 0; i-- {
+		if r, _ := tb.Take("a", now); !r.Allowed || r.Remaining != i {
+			t.Fatalf("burst request should pass with %d remaining, got %+v", i, r)
+		}
+	}
+	r, _ := tb.Take("a", now)
+	if r.Allowed || r.RetryAfter != time.Second || !r.Reset.Equal(now.Add(3*time.Second)) {
+		t.Fatalf("empty bucket should wait for a refill, got %+v", r)
+	}
+	if r, _ := tb.Take("b", now); !r.Allowed {
+		t.Fatal("keys should have their own bucket")
+	}
+	if r, _ := tb.Take("a", now.Add(1500*time.Millisecond)); !r.Allowed || r.Remaining != 0 {
+		t.Fatalf("refilled token should pass, got %+v", r)
+	}
+
+	tb.Take("c", now.Add(time.Hour))
+	if tb.Len() != 1 {
+		t.Fatalf("idle buckets should be evicted, %d left", tb.Len())
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	sw := NewSlidingWindow(4, time.Minute)
+	start := time.Unix(6000, 0) // a window start
+	for i := 0; i < 4; i++ {
+		if r, _ := sw.Take("a", start.Add(50*time.Second)); !r.Allowed || r.Remaining != 3-i {
+			t.Fatalf("request %d should pass, got %+v", i, r)
+		}
+	}
+	r, _ := sw.Take("a", start.Add(50*time.Second))
+	if r.Allowed || r.RetryAfter != 25*time.Second {
+		t.Fatalf("full window should reject, got %+v", r)
+	}
+
+	// at 75s the previous window weighs 0.75, so 3 requests count
+	if r, _ := sw.Take("a", start.Add(75*time.Second)); !r.Allowed || r.Remaining != 0 {
+		t.Fatalf("sliding window should free one request, got %+v", r)
+	}
+	if r, _ := sw.Take("a", start.Add(76*time.Second)); r.Allowed {
+		t.Fatalf("sliding window should be full again, got %+v", r)
+	}
+	if r, _ := sw.Take("a", start.Add(3*time.Minute)); !r.Allowed || r.Remaining != 3 {
+		t.Fatalf("old windows should be forgotten, got %+v", r)
+	}
+
+	sw.Take("b", start.Add(time.Hour))
+	if sw.Len() != 1 {
+		t.Fatalf("idle counters should be evicted, %d left", sw.Len())
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	r := gee.New()
+	api := r.Group("/api")
+	api.Use(RateLimit(RateLimitConfig{Store: NewTokenBucket(0.5, 2), KeyFunc: KeyByHeader("X-API-Key")}))
+	api.GET("/", func(c *gee.Context) { c.String(http.StatusOK, "ok") })
+
+	get := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/", nil)
+		req.Header.Set("X-API-Key", key)
+		return serve(r, req)
+	}
+	get("k1")
+	if w := get("k1"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "2" ||
+		w.Header().Get("X-RateLimit-Remaining") != "0" || w.Header().Get("X-RateLimit-Reset") == "" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	w := get("k1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Fatalf("limited request should get 429, got %d %v", w.Code, w.Header())
+	}
+	if w := get(""); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+		t.Fatal("requests without key shouldn't be limited")
+	}
+	if w := get("k2"); w.Code != http.StatusOK {
+		t.Fatalf("other keys should pass, got %d", w.Code)
+	}
+}
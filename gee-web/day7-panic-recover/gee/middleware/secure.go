@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"gee"
+)
+
+// SecureConfig configures the security headers, empty values are not sent
+type SecureConfig struct {
+	// HSTSMaxAge sets Strict-Transport-Security on HTTPS requests,
+	// including the ones with X-Forwarded-Proto: https
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// ContentSecurityPolicy is the Content-Security-Policy header
+	ContentSecurityPolicy string
+	// FrameOptions is the X-Frame-Options header, DENY or SAMEORIGIN
+	FrameOptions string
+	// ContentTypeNosniff sets X-Content-Type-Options: nosniff
+	ContentTypeNosniff bool
+	// ReferrerPolicy is the Referrer-Policy header
+	ReferrerPolicy string
+}
+
+// DefaultSecureConfig is a strict baseline for applications not embedded in frames
+func DefaultSecureConfig() SecureConfig {
+	return SecureConfig{
+		HSTSMaxAge:            365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		ContentSecurityPolicy: "default-src 'self'",
+		FrameOptions:          "DENY",
+		ContentTypeNosniff:    true,
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+	}
+}
+
+// Secure adds the security headers of config to every response
+func Secure(config SecureConfig) gee.HandlerFunc {
+	hsts := ""
+	if config.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(config.HSTSMaxAge/time.Second), 10)
+		if config.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if config.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	return func(c *gee.Context) {
+		header := c.Writer.Header()
+		if hsts != "" && (c.Req.TLS != nil || c.Req.Header.Get("X-Forwarded-Proto") == "https") {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		if config.ContentSecurityPolicy != "" {
+			header.Set("Content-Security-Policy", config.ContentSecurityPolicy)
+		}
+		if config.FrameOptions != "" {
+			header.Set("X-Frame-Options", config.FrameOptions)
+		}
+		if config.ContentTypeNosniff {
+			header.Set("X-Content-Type-Options", "nosniff")
+		}
+		if config.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", config.ReferrerPolicy)
+		}
+		c.Next()
+	}
+}
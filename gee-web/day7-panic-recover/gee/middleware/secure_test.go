@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"gee"
+)
+
+func TestSecure(t *testing.T) {
+	r := gee.New()
+	v1 := r.Group("/v1")
+	v1.Use(Secure(DefaultSecureConfig()))
+	v1.GET("/", func(c *gee.Context) {})
+	r.GET("/", func(c *gee.Context) {})
+
+	w := serve(r, httptest.NewRequest("GET", "/v1/", nil))
+	for k, v := range map[string]string{
+		"Content-Security-Policy": "default-src 'self'",
+		"X-Frame-Options":         "DENY",
+		"X-Content-Type-Options":  "nosniff",
+		"Referrer-Policy":         "strict-origin-when-cross-origin",
+	} {
+		if got := w.Header().Get(k); got != v {
+			t.Fatalf("%s should be %q, got %q", k, v, got)
+		}
+	}
+	if w.Header().Get("Strict-Transport-Security") != "" {
+		t.Fatal("HSTS shouldn't be sent over plain HTTP")
+	}
+
+	req := httptest.NewRequest("GET", "https://example.com/v1/", nil)
+	if w := serve(r, req); w.Header().Get("Strict-Transport-Security") != "max-age=31536000; includeSubDomains" {
+		t.Fatalf("unexpected HSTS header %q", w.Header().Get("Strict-Transport-Security"))
+	}
+
+	if w := serve(r, httptest.NewRequest("GET", "/", nil)); w.Header().Get("X-Frame-Options") != "" {
+		t.Fatal("routes outside the group shouldn't get the headers")
+	}
+}
@@ -0,0 +1,166 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// marshalMsgPack encodes v as MessagePack. Structs are encoded as maps using
+// the `msgpack` tag, []byte as bin and time.Time as the timestamp extension.
+func marshalMsgPack(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeMsgPack(&buf, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeMsgPackUint(buf *bytes.Buffer, n uint64) {
+	switch {
+	case n <= 0x7f:
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{0xcc, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xcd)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buf.WriteByte(0xce)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(0xcf)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+func writeMsgPackInt(buf *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0:
+		writeMsgPackUint(buf, uint64(n))
+	case n >= -32:
+		buf.WriteByte(byte(n))
+	case n >= math.MinInt8:
+		buf.Write([]byte{0xd0, byte(n)})
+	case n >= math.MinInt16:
+		buf.WriteByte(0xd1)
+		binary.Write(buf, binary.BigEndian, int16(n))
+	case n >= math.MinInt32:
+		buf.WriteByte(0xd2)
+		binary.Write(buf, binary.BigEndian, int32(n))
+	default:
+		buf.WriteByte(0xd3)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+// writeMsgPackHeader writes the header of a str, bin, array or map of length n,
+// codes holds the fix prefix (0 if none) and the 8, 16 and 32 bit forms (0 if none)
+func writeMsgPackHeader(buf *bytes.Buffer, n int, fix byte, fixMax int, codes [3]byte) {
+	switch {
+	case fix != 0 && n <= fixMax:
+		buf.WriteByte(fix | byte(n))
+	case codes[0] != 0 && n <= math.MaxUint8:
+		buf.Write([]byte{codes[0], byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(codes[1])
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(codes[2])
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+func writeMsgPackString(buf *bytes.Buffer, s string) {
+	writeMsgPackHeader(buf, len(s), 0xa0, 31, [3]byte{0xd9, 0xda, 0xdb})
+	buf.WriteString(s)
+}
+
+func writeMsgPack(buf *bytes.Buffer, v reflect.Value) error {
+	v = indirect(v)
+	if !v.IsValid() {
+		buf.WriteByte(0xc0)
+		return nil
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		// timestamp 96: nanoseconds then seconds, extension type -1
+		buf.Write([]byte{0xc7, 12, 0xff})
+		binary.Write(buf, binary.BigEndian, uint32(t.Nanosecond()))
+		binary.Write(buf, binary.BigEndian, t.Unix())
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeMsgPackInt(buf, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeMsgPackUint(buf, v.Uint())
+	case reflect.Float32:
+		buf.WriteByte(0xca)
+		binary.Write(buf, binary.BigEndian, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		buf.WriteByte(0xcb)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(v.Float()))
+	case reflect.String:
+		writeMsgPackString(buf, v.String())
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			writeMsgPackHeader(buf, len(b), 0, 0, [3]byte{0xc4, 0xc5, 0xc6})
+			buf.Write(b)
+			return nil
+		}
+		writeMsgPackHeader(buf, v.Len(), 0x90, 15, [3]byte{0, 0xdc, 0xdd})
+		for i := 0; i < v.Len(); i++ {
+			if err := writeMsgPack(buf, v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = fmt.Sprint(k.Interface())
+		}
+		sort.Sort(byName{names, keys})
+		writeMsgPackHeader(buf, len(keys), 0x80, 15, [3]byte{0, 0xde, 0xdf})
+		for _, k := range keys {
+			if err := writeMsgPack(buf, k); err != nil {
+				return err
+			}
+			if err := writeMsgPack(buf, v.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		fields := encodedFields(v.Type(), "msgpack")
+		kept := fields[:0]
+		for _, f := range fields {
+			if !f.omitEmpty || !v.Field(f.index).IsZero() {
+				kept = append(kept, f)
+			}
+		}
+		writeMsgPackHeader(buf, len(kept), 0x80, 15, [3]byte{0, 0xde, 0xdf})
+		for _, f := range kept {
+			writeMsgPackString(buf, f.name)
+			if err := writeMsgPack(buf, v.Field(f.index)); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("gee: can't encode %s as MessagePack", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package gee
+
+import (
+	"strconv"
+	"strings"
+)
+
+// acceptRange is a media range of the Accept header with its quality
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+func parseAccept(accept string) []acceptRange {
+	ranges := make([]acceptRange, 0)
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		if mediaType == "" {
+			continue
+		}
+		q := 1.0
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+// matchQuality returns the quality the most specific range of ranges gives to
+// offer, -1 if none matches
+func matchQuality(ranges []acceptRange, offer string) float64 {
+	q, specificity := -1.0, -1
+	slash := strings.IndexByte(offer, '/')
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.mediaType == offer:
+			s = 2
+		case slash > 0 && r.mediaType == offer[:slash]+"/*":
+			s = 1
+		case r.mediaType == "*/*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// negotiateFormat returns the offer the Accept header prefers, ties going to
+// the earlier offer. An empty header accepts the first offer, "" means none is acceptable.
+func negotiateFormat(accept string, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := matchQuality(ranges, strings.ToLower(offer)); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,121 @@
+package gee
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// constraint restricts the values a {name:expr} route param matches.
+// expr is "int", "uuid" or a regular expression matching the whole value.
+type constraint struct {
+	expr  string
+	match func(value string) bool
+}
+
+func newConstraint(pattern string, expr string) *constraint {
+	switch expr {
+	case "int":
+		return &constraint{expr: expr, match: isInt}
+	case "uuid":
+		return &constraint{expr: expr, match: isUUID}
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		panic(fmt.Sprintf("gee: invalid constraint %s in route %s: %v", expr, pattern, err))
+	}
+	return &constraint{expr: expr, match: re.MatchString}
+}
+
+// splitWildcard returns the param name and constraint expression of a
+// wildcard part: ":name", "*name", "{name}" or "{name:expr}"
+func splitWildcard(part string) (key string, expr string) {
+	if part[0] != '{' {
+		return part[1:], ""
+	}
+	key = strings.TrimSuffix(part[1:], "}")
+	if i := strings.IndexByte(key, ':'); i >= 0 {
+		key, expr = key[:i], key[i+1:]
+	}
+	return key, expr
+}
+
+func isInt(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+func isUUID(s string) bool {
+	_, err := ParseUUID(s)
+	return err == nil
+}
+
+// UUID is a 128 bits universally unique identifier
+type UUID [16]byte
+
+// ErrInvalidUUID is returned when parsing a malformed UUID
+var ErrInvalidUUID = errors.New("gee: invalid UUID")
+
+// ParseUUID parses the canonical form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+// of a UUID, in either case
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, ErrInvalidUUID
+	}
+	for i, j := 0, 0; i < len(s); {
+		if i == 8 || i == 13 || i == 18 || i == 23 {
+			i++
+			continue
+		}
+		hi, ok := fromHex(s[i])
+		lo, ok2 := fromHex(s[i+1])
+		if !ok || !ok2 {
+			return UUID{}, ErrInvalidUUID
+		}
+		u[j] = hi<<4 | lo
+		i, j = i+2, j+1
+	}
+	return u, nil
+}
+
+func fromHex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// String returns the canonical lower case form of u
+func (u UUID) String() string {
+	s := hex.EncodeToString(u[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// ParamInt returns the route param key as an int, routes declaring it
+// {key:int} guarantee it succeeds
+func (c *Context) ParamInt(key string) (int, error) {
+	n, err := strconv.Atoi(c.Param(key))
+	if err != nil {
+		return 0, fmt.Errorf("gee: param %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// ParamUUID returns the route param key as a UUID, routes declaring it
+// {key:uuid} guarantee it succeeds
+func (c *Context) ParamUUID(key string) (UUID, error) {
+	u, err := ParseUUID(c.Param(key))
+	if err != nil {
+		return UUID{}, fmt.Errorf("%w: param %s", err, key)
+	}
+	return u, nil
+}
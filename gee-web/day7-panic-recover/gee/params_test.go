@@ -0,0 +1,60 @@
+package gee
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseUUID(t *testing.T) {
+	u, err := ParseUUID("6BA7B810-9dad-11d1-80b4-00c04fd430c8")
+	if err != nil || u.String() != "6ba7b810-9dad-11d1-80b4-00c04fd430c8" || u[0] != 0x6b || u[15] != 0xc8 {
+		t.Fatalf("unexpected UUID %s %v", u, err)
+	}
+	for _, s := range []string{"", "6ba7b810-9dad-11d1-80b4-00c04fd430c", "6ba7b810-9dad-11d1-80b4x00c04fd430c8",
+		"6ba7b810-9dad-11d1-80b4-00c04fd430cg", "6ba7b8109dad-11d1-80b4-00c04fd430c8-"} {
+		if _, err := ParseUUID(s); err != ErrInvalidUUID {
+			t.Fatalf("%q should be invalid, got %v", s, err)
+		}
+	}
+}
+
+func TestParamAccessors(t *testing.T) {
+	r := New()
+	r.GET("/user/{id:int}/doc/{doc:uuid}", func(c *Context) {
+		id, err := c.ParamInt("id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := c.ParamUUID("doc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.String(http.StatusOK, "%d %s", id, doc)
+	})
+	r.GET("/user/:name", func(c *Context) {
+		_, err := c.ParamInt("name")
+		_, err2 := c.ParamUUID("name")
+		if err == nil || !errors.Is(err2, ErrInvalidUUID) {
+			t.Fatalf("%s isn't an int or a UUID, got %v %v", c.Param("name"), err, err2)
+		}
+		c.String(http.StatusOK, c.FullPath())
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/user/42/doc/6BA7B810-9DAD-11D1-80B4-00C04FD430C8", nil))
+	if w.Body.String() != "42 6ba7b810-9dad-11d1-80b4-00c04fd430c8" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/user/geektutu", nil))
+	if w.Body.String() != "/user/:name" {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/user/42/doc/readme", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("a doc that isn't a UUID should be 404, got %d", w.Code)
+	}
+}
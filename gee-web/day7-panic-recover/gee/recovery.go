@@ -1,6 +1,7 @@
 package gee
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -29,7 +30,11 @@ func Recovery() HandlerFunc {
 			if err := recover(); err != nil {
 				message := fmt.Sprintf("%s", err)
 				log.Printf("%s\n\n", trace(message))
-				c.Fail(http.StatusInternalServerError, "Internal Server Error")
+				c.AbortWithError(http.StatusInternalServerError, errors.New(message))
+				// once the response has started, a 500 body would corrupt it
+				if !c.Writer.Written() {
+					renderErrors(c)
+				}
 			}
 		}()
 
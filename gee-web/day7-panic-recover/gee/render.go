@@ -0,0 +1,426 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Render writes a response body in some format
+type Render interface {
+	// Render writes the body, it may fail before writing anything
+	Render(w http.ResponseWriter) error
+	// WriteContentType sets the Content-Type header of the format
+	WriteContentType(w http.ResponseWriter)
+}
+
+func writeContentType(w http.ResponseWriter, value string) {
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", value)
+	}
+}
+
+// bodyAllowedForStatus reports whether a response with code may have a body
+func bodyAllowedForStatus(code int) bool {
+	switch {
+	case code >= 100 && code <= 199:
+		return false
+	case code == http.StatusNoContent, code == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
+// Render writes the response with status code using r. A render failure is
+// attached with c.Error and aborts the chain, so that ErrorHandler can report it.
+func (c *Context) Render(code int, r Render) {
+	c.Status(code)
+	r.WriteContentType(c.Writer)
+	if !bodyAllowedForStatus(code) || c.Method == "HEAD" {
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	if err := r.Render(c.Writer); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		// nothing was sent yet, answer 500 instead of an empty 200
+		if !c.Writer.Written() {
+			renderErrors(c)
+		}
+	}
+}
+
+// String renders fmt.Sprintf(Format, Data...) as text/plain
+type String struct {
+	Format string
+	Data   []interface{}
+}
+
+func (r String) Render(w http.ResponseWriter) error {
+	_, err := fmt.Fprintf(w, r.Format, r.Data...)
+	return err
+}
+
+func (r String) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "text/plain")
+}
+
+// Data renders raw bytes, ContentType is only set when not empty
+type Data struct {
+	ContentType string
+	Data        []byte
+}
+
+func (r Data) Render(w http.ResponseWriter) error {
+	_, err := w.Write(r.Data)
+	return err
+}
+
+func (r Data) WriteContentType(w http.ResponseWriter) {
+	if r.ContentType != "" {
+		writeContentType(w, r.ContentType)
+	}
+}
+
+// JSON renders Data as JSON
+type JSON struct {
+	Data interface{}
+}
+
+func (r JSON) Render(w http.ResponseWriter) error {
+	b, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func (r JSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/json")
+}
+
+// IndentedJSON renders Data as human readable JSON
+type IndentedJSON struct {
+	Data interface{}
+}
+
+func (r IndentedJSON) Render(w http.ResponseWriter) error {
+	b, err := json.MarshalIndent(r.Data, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func (r IndentedJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/json")
+}
+
+// SecureJSON prefixes JSON arrays with Prefix to prevent JSON hijacking
+type SecureJSON struct {
+	Prefix string
+	Data   interface{}
+}
+
+func (r SecureJSON) Render(w http.ResponseWriter) error {
+	b, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	if bytes.HasPrefix(b, []byte("[")) {
+		b = append([]byte(r.Prefix), b...)
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func (r SecureJSON) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/json")
+}
+
+// JSONP renders Data as a call of the javascript function Callback
+type JSONP struct {
+	Callback string
+	Data     interface{}
+}
+
+func (r JSONP) Render(w http.ResponseWriter) error {
+	b, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	if r.Callback == "" {
+		_, err = w.Write(append(b, '\n'))
+		return err
+	}
+	callback := template.JSEscapeString(r.Callback)
+	_, err = fmt.Fprintf(w, "%s(%s);", callback, b)
+	return err
+}
+
+func (r JSONP) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/javascript")
+}
+
+// XML renders Data with encoding/xml
+type XML struct {
+	Data interface{}
+}
+
+func (r XML) Render(w http.ResponseWriter) error {
+	b, err := xml.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (r XML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/xml; charset=utf-8")
+}
+
+// MarshalXML encodes h as an element holding one child per key, in sorted
+// order, so that H can be rendered as XML: H{"a": 1} is <H><a>1</a></H>
+func (h H) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := e.EncodeElement(h[k], xml.StartElement{Name: xml.Name{Local: k}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// YAML renders Data as a YAML document, see marshalYAML
+type YAML struct {
+	Data interface{}
+}
+
+func (r YAML) Render(w http.ResponseWriter) error {
+	b, err := marshalYAML(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (r YAML) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/x-yaml; charset=utf-8")
+}
+
+// MsgPack renders Data as MessagePack, see marshalMsgPack
+type MsgPack struct {
+	Data interface{}
+}
+
+func (r MsgPack) Render(w http.ResponseWriter) error {
+	b, err := marshalMsgPack(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (r MsgPack) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/msgpack")
+}
+
+// ProtoMarshaler is implemented by generated protobuf messages that can
+// encode themselves, such as the gogo/protobuf ones
+type ProtoMarshaler interface {
+	Marshal() ([]byte, error)
+}
+
+// ProtoMarshal encodes messages not implementing ProtoMarshaler,
+// set it to proto.Marshal of the protobuf module in use
+var ProtoMarshal func(message interface{}) ([]byte, error)
+
+// ProtoBuf renders Data as a protobuf message
+type ProtoBuf struct {
+	Data interface{}
+}
+
+func (r ProtoBuf) Render(w http.ResponseWriter) error {
+	var b []byte
+	var err error
+	if m, ok := r.Data.(ProtoMarshaler); ok {
+		b, err = m.Marshal()
+	} else if ProtoMarshal != nil {
+		b, err = ProtoMarshal(r.Data)
+	} else {
+		err = fmt.Errorf("gee: %T is not a ProtoMarshaler and ProtoMarshal is not set", r.Data)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (r ProtoBuf) WriteContentType(w http.ResponseWriter) {
+	writeContentType(w, "application/x-protobuf")
+}
+
+func (c *Context) String(code int, format string, values ...interface{}) {
+	c.Render(code, String{Format: format, Data: values})
+}
+
+func (c *Context) JSON(code int, obj interface{}) {
+	c.Render(code, JSON{Data: obj})
+}
+
+// IndentedJSON renders obj as human readable JSON
+func (c *Context) IndentedJSON(code int, obj interface{}) {
+	c.Render(code, IndentedJSON{Data: obj})
+}
+
+// SecureJSON renders obj as JSON, prefixing arrays with "while(1);"
+func (c *Context) SecureJSON(code int, obj interface{}) {
+	c.Render(code, SecureJSON{Prefix: "while(1);", Data: obj})
+}
+
+// JSONP renders obj wrapped in the function named by the callback query param,
+// plain JSON when there is none
+func (c *Context) JSONP(code int, obj interface{}) {
+	c.Render(code, JSONP{Callback: c.Query("callback"), Data: obj})
+}
+
+func (c *Context) XML(code int, obj interface{}) {
+	c.Render(code, XML{Data: obj})
+}
+
+func (c *Context) YAML(code int, obj interface{}) {
+	c.Render(code, YAML{Data: obj})
+}
+
+func (c *Context) MsgPack(code int, obj interface{}) {
+	c.Render(code, MsgPack{Data: obj})
+}
+
+func (c *Context) ProtoBuf(code int, obj interface{}) {
+	c.Render(code, ProtoBuf{Data: obj})
+}
+
+func (c *Context) Data(code int, data []byte) {
+	c.Render(code, Data{Data: data})
+}
+
+// RendererFactory returns the Render writing data in one format
+type RendererFactory func(data interface{}) Render
+
+var (
+	renderersMu sync.RWMutex
+	renderers   = map[string]RendererFactory{
+		"application/json":       func(data interface{}) Render { return JSON{Data: data} },
+		"application/xml":        func(data interface{}) Render { return XML{Data: data} },
+		"text/xml":               func(data interface{}) Render { return XML{Data: data} },
+		"application/x-yaml":     func(data interface{}) Render { return YAML{Data: data} },
+		"application/yaml":       func(data interface{}) Render { return YAML{Data: data} },
+		"application/x-protobuf": func(data interface{}) Render { return ProtoBuf{Data: data} },
+		"application/msgpack":    func(data interface{}) Render { return MsgPack{Data: data} },
+		"application/x-msgpack":  func(data interface{}) Render { return MsgPack{Data: data} },
+		"text/plain":             func(data interface{}) Render { return String{Format: "%v", Data: []interface{}{data}} },
+	}
+)
+
+// RegisterRenderer makes mimeType available to c.Negotiate,
+// replacing the built-in renderer if there is one
+func RegisterRenderer(mimeType string, factory RendererFactory) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	renderers[mimeType] = factory
+}
+
+func lookupRenderer(mimeType string) (RendererFactory, bool) {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+	factory, ok := renderers[mimeType]
+	return factory, ok
+}
+
+// Negotiate offers the same data in several formats
+type Negotiate struct {
+	Offered []string    // MIME types with a registered renderer, preferred first
+	Data    interface{} // data rendered in the chosen format
+}
+
+// ErrNotAcceptable is attached by c.Negotiate when no offered format is acceptable
+var ErrNotAcceptable = errors.New("gee: no acceptable format offered")
+
+// Negotiate renders n.Data in the offered format the Accept header prefers,
+// honoring quality values. If none is acceptable it aborts with a 406 error.
+func (c *Context) Negotiate(code int, n Negotiate) {
+	format := negotiateFormat(c.Req.Header.Get("Accept"), n.Offered...)
+	if format == "" {
+		c.AbortWithProblem(http.StatusNotAcceptable, ErrNotAcceptable)
+		return
+	}
+	factory, ok := lookupRenderer(format)
+	if !ok {
+		panic("gee: no renderer registered for " + format)
+	}
+	c.SetHeader("Vary", "Accept")
+	c.Render(code, factory(n.Data))
+}
+
+// indirect dereferences pointers and interfaces, the result is invalid for nil
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// encodedField is an exported struct field as seen by the YAML and MsgPack encoders
+type encodedField struct {
+	name      string
+	index     int
+	omitEmpty bool
+}
+
+// encodedFields lists the fields of struct type t, named by tag (with the
+// ",omitempty" option) or by the lowercased field name. Fields tagged "-" are skipped.
+func encodedFields(t reflect.Type, tag string) []encodedField {
+	fields := make([]encodedField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name, opts := field.Tag.Get(tag), ""
+		if comma := strings.IndexByte(name, ','); comma >= 0 {
+			name, opts = name[:comma], name[comma+1:]
+		}
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields = append(fields, encodedField{name: name, index: i, omitEmpty: opts == "omitempty"})
+	}
+	return fields
+}
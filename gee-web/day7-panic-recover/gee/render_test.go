@@ -0,0 +1,157 @@
+package gee
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type renderUser struct {
+	Name  string   `json:"name" xml:"name" yaml:"name" msgpack:"name"`
+	Age   int      `json:"age" xml:"age" yaml:"age,omitempty" msgpack:"age"`
+	Tags  []string `json:"tags" xml:"tag" yaml:"tags" msgpack:"-"`
+	Extra H        `json:"-" xml:"-" yaml:"extra" msgpack:"-"`
+}
+
+type fakeProto struct{}
+
+func (fakeProto) Marshal() ([]byte, error) { return []byte{0x0a, 0x03, 'g', 'e', 'e'}, nil }
+
+func TestNegotiate(t *testing.T) {
+	RegisterRenderer("text/csv", func(data interface{}) Render {
+		u := data.(renderUser)
+		return Data{ContentType: "text/csv", Data: []byte(u.Name + "," + "20\n")}
+	})
+	r := New()
+	r.GET("/user", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{
+			Offered: []string{"application/json", "application/xml", "application/x-yaml", "text/csv"},
+			Data:    renderUser{Name: "geektutu", Age: 20},
+		})
+	})
+
+	cases := []struct {
+		accept string
+		code   int
+		body   string
+	}{
+		{"", 200, `{"name":"geektutu","age":20,"tags":null}` + "\n"},
+		{"application/json;q=0.5, application/xml", 200, `<renderUser><name>geektutu</name><age>20</age></renderUser>`},
+		{"application/*;q=0.1, application/x-yaml", 200, "name: geektutu\nage: 20\ntags: []\nextra: {}\n"},
+		{"text/csv, */*;q=0.1", 200, "geektutu,20\n"},
+		{"image/png", 406, ""},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/user", nil)
+		req.Header.Set("Accept", c.accept)
+		r.ServeHTTP(w, req)
+		if w.Code != c.code || (c.body != "" && w.Body.String() != c.body) {
+			t.Fatalf("Accept %q should render %d %q, got %d %q", c.accept, c.code, c.body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestNegotiateH(t *testing.T) {
+	r := New()
+	r.GET("/", func(c *Context) {
+		c.Negotiate(http.StatusOK, Negotiate{
+			Offered: []string{"application/json", "application/xml"},
+			Data:    H{"name": "geektutu", "user": H{"age": 20, "tags": []string{"a", "b"}}},
+		})
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/xml")
+	r.ServeHTTP(w, req)
+	want := `<H><name>geektutu</name><user><age>20</age><tags>a</tags><tags>b</tags></user></H>`
+	if w.Code != http.StatusOK || w.Body.String() != want {
+		t.Fatalf("H should render as XML, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestRenderers(t *testing.T) {
+	render := func(handler HandlerFunc, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(newContext(w, httptest.NewRequest("GET", target, nil)))
+		return w
+	}
+	cases := []struct {
+		name    string
+		handler HandlerFunc
+		target  string
+		ct      string
+		body    string
+	}{
+		{"secure json", func(c *Context) { c.SecureJSON(200, []int{1, 2}) }, "/", "application/json", "while(1);[1,2]\n"},
+		{"jsonp", func(c *Context) { c.JSONP(200, H{"a": 1}) }, "/?callback=cb", "application/javascript", `cb({"a":1});`},
+		{"indented json", func(c *Context) { c.IndentedJSON(200, H{"a": 1}) }, "/", "application/json", "{\n    \"a\": 1\n}\n"},
+		{"protobuf", func(c *Context) { c.ProtoBuf(200, fakeProto{}) }, "/", "application/x-protobuf", "\x0a\x03gee"},
+		{"yaml", func(c *Context) {
+			c.YAML(200, []interface{}{"true", H{"users": []renderUser{{Name: "a: b", Tags: []string{"x"}}}}})
+		}, "/", "application/x-yaml; charset=utf-8", "- \"true\"\n- users:\n    - name: \"a: b\"\n      tags:\n        - x\n      extra: {}\n"},
+	}
+	for _, c := range cases {
+		w := render(c.handler, c.target)
+		if w.Header().Get("Content-Type") != c.ct || w.Body.String() != c.body {
+			t.Fatalf("%s: unexpected %s %q", c.name, w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+}
+
+func TestMarshalMsgPack(t *testing.T) {
+	b, err := marshalMsgPack(H{"user": renderUser{Name: "gee", Age: -1}, "n": 300, "bin": []byte{1}, "at": time.Unix(1, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0x84,
+		0xa2, 'a', 't', 0xc7, 12, 0xff, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1,
+		0xa3, 'b', 'i', 'n', 0xc4, 1, 1,
+		0xa1, 'n', 0xcd, 0x01, 0x2c,
+		0xa4, 'u', 's', 'e', 'r', 0x82, 0xa4, 'n', 'a', 'm', 'e', 0xa3, 'g', 'e', 'e', 0xa3, 'a', 'g', 'e', 0xff,
+	}
+	if !bytes.Equal(b, want) {
+		t.Fatalf("unexpected msgpack\n% x\n% x", b, want)
+	}
+}
+
+func TestYAMLQuoting(t *testing.T) {
+	b, err := marshalYAML(H{"a": "foo:"})
+	if err != nil || string(b) != "a: \"foo:\"\n" {
+		t.Fatalf("a string ending in a colon should be quoted, got %q %v", b, err)
+	}
+	for _, s := range []string{"foo:", ".inf", "-.Inf", ".NaN", ".5", "0x1F", "0o17", "017", "0b101", "1_000", "+12", "1e3", "1:30", "Null", "~"} {
+		if q := yamlString(s); q != strconv.Quote(s) {
+			t.Fatalf("%s should be quoted, got %s", s, q)
+		}
+	}
+	for _, s := range []string{"foo", "a:b", "v1.2", "1a2b3c4g", "gee.example"} {
+		if q := yamlString(s); q != s {
+			t.Fatalf("%s shouldn't be quoted, got %s", s, q)
+		}
+	}
+}
+
+func TestRenderNoBody(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := newContext(w, httptest.NewRequest("GET", "/", nil))
+	c.JSON(http.StatusNoContent, H{"a": 1})
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Fatalf("204 shouldn't have a body, got %q", w.Body.String())
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	r := New()
+	r.GET("/", func(c *Context) {
+		c.JSON(http.StatusOK, H{"f": func() {}})
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("a render error should be a 500 problem, got %d %q %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+}
@@ -0,0 +1,54 @@
+package gee
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	// RequestIDHeader is the header carrying the request id
+	RequestIDHeader = "X-Request-ID"
+	requestIDKey    = "gee/request-id"
+)
+
+// RequestID reuses the X-Request-ID of the request, or generates one when it
+// is missing or invalid. The id is echoed in the response and returned by
+// c.RequestID for handlers and the access log.
+func RequestID() HandlerFunc {
+	return func(c *Context) {
+		id := c.Req.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set(requestIDKey, id)
+		c.SetHeader(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// RequestID returns the id set by the RequestID middleware, empty without it
+func (c *Context) RequestID() string {
+	id, _ := c.Get(requestIDKey)
+	s, _ := id.(string)
+	return s
+}
+
+// validRequestID accepts ids of up to 128 printable ASCII characters,
+// so that clients can't inject arbitrary data into logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,99 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseWriterStatus(t *testing.T) {
+	r := New()
+	var status, size int
+	r.Use(func(c *Context) {
+		c.Next()
+		status, size = c.Writer.Status(), c.Writer.Size()
+	})
+	r.GET("/direct", func(c *Context) {
+		c.Writer.WriteHeader(http.StatusAccepted)
+		c.Writer.Write([]byte("hello"))
+	})
+	r.GET("/empty", func(c *Context) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/direct", nil))
+	if status != http.StatusAccepted || size != 5 {
+		t.Fatalf("should record 202 and 5 bytes, got %d and %d", status, size)
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/empty", nil))
+	if status != http.StatusOK || size != -1 {
+		t.Fatalf("empty handler should default to 200 without body, got %d and %d", status, size)
+	}
+}
+
+func TestResponseWriterHeaderAfterStatus(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := newContext(w, httptest.NewRequest("GET", "/", nil))
+	c.Status(http.StatusCreated)
+	c.SetHeader("Location", "/users/1")
+	c.Writer.Write([]byte("created"))
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/users/1" {
+		t.Fatalf("headers set before the first write should be sent, got %d %v", w.Code, w.Header())
+	}
+	c.Status(http.StatusInternalServerError)
+	if c.Writer.Status() != http.StatusCreated {
+		t.Fatal("status shouldn't change once written")
+	}
+}
+
+func TestRecoveryAfterWrite(t *testing.T) {
+	r := New()
+	r.Use(Recovery())
+	r.GET("/panic", func(c *Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "partial" {
+		t.Fatalf("recovery shouldn't write once the response started, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestResponseWriterInterfaces(t *testing.T) {
+	var hijacked bool
+	r := New()
+	r.GET("/hijack", func(c *Context) {
+		conn, _, err := c.Writer.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		hijacked = true
+		conn.Write([]byte("HTTP/1.1 204 No Content\r\n\r\n"))
+		conn.Close()
+	})
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/hijack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if !hijacked || res.StatusCode != http.StatusNoContent {
+		t.Fatalf("hijack should work through the wrapped writer, got %d", res.StatusCode)
+	}
+
+	// httptest.ResponseRecorder supports none of Hijacker and Pusher
+	c := newContext(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if _, _, err := c.Writer.Hijack(); err != http.ErrNotSupported {
+		t.Fatalf("Hijack should be ErrNotSupported, got %v", err)
+	}
+	if err := c.Writer.Push("/style.css", nil); err != http.ErrNotSupported {
+		t.Fatalf("Push should be ErrNotSupported, got %v", err)
+	}
+	c.Writer.Flush()
+	if !c.Writer.Written() {
+		t.Fatal("Flush should send the status line")
+	}
+}
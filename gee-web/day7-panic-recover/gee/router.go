@@ -1,19 +1,47 @@
 package gee
 
 import (
-	"net/http"
+	"fmt"
+	"sort"
 	"strings"
 )
 
+// anyMethods are the methods registered by RouterGroup.Any
+var anyMethods = []string{"GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS"}
+
+// Param is a single route parameter, consisting of a key and a value
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params is the ordered list of route parameters matched for a request
+type Params []Param
+
+// Get returns the value of the first param whose key matches name
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// ByName returns the value of the param name, or "" if there is none
+func (ps Params) ByName(name string) string {
+	value, _ := ps.Get(name)
+	return value
+}
+
 type router struct {
-	roots    map[string]*node
-	handlers map[string]HandlerFunc
+	roots     map[string]*node
+	maxParams int // capacity a Params needs so that lookups never grow it
 }
 
 func newRouter() *router {
 	return &router{
-		roots:    make(map[string]*node),
-		handlers: make(map[string]HandlerFunc),
+		roots: make(map[string]*node),
 	}
 }
 
@@ -33,44 +61,97 @@ func parsePattern(pattern string) []string {
 	return parts
 }
 
-func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
-	parts := parsePattern(pattern)
+// validatePattern rejects patterns parsePattern would silently truncate or misread
+func validatePattern(pattern string) {
+	if pattern == "" || pattern[0] != '/' {
+		panic(fmt.Sprintf("gee: route %q must begin with '/'", pattern))
+	}
+	vs := strings.Split(pattern, "/")
+	for i, item := range vs {
+		if item == "" {
+			continue
+		}
+		if item == ":" {
+			panic(fmt.Sprintf("gee: wildcard ':' must be named in route %s", pattern))
+		}
+		if item[0] == '{' {
+			key, expr := splitWildcard(item)
+			if !strings.HasSuffix(item, "}") || key == "" || (expr == "" && strings.Contains(item, ":")) {
+				panic(fmt.Sprintf("gee: wildcard %s in route %s must be a whole segment {name} or {name:constraint}", item, pattern))
+			}
+		}
+		if item[0] == '*' && strings.Trim(strings.Join(vs[i+1:], ""), "/") != "" {
+			panic(fmt.Sprintf("gee: catch-all %s must be the last part of route %s", item, pattern))
+		}
+	}
+}
+
+func (r *router) addRoute(method string, pattern string, handlers []HandlerFunc) *node {
+	validatePattern(pattern)
+	pattern = cleanPath(pattern)
 
-	key := method + "-" + pattern
 	_, ok := r.roots[method]
 	if !ok {
 		r.roots[method] = &node{}
 	}
-	r.roots[method].insert(pattern, parts, 0)
-	r.handlers[key] = handler
+	n := r.roots[method].insert(pattern, handlers)
+
+	wilds := 0
+	for _, part := range parsePattern(pattern) {
+		if part[0] == ':' || part[0] == '*' || part[0] == '{' {
+			wilds++
+		}
+	}
+	if wilds > r.maxParams {
+		r.maxParams = wilds
+	}
+	return n
 }
 
-func (r *router) getRoute(method string, path string) (*node, map[string]string) {
-	searchParts := parsePattern(path)
-	params := make(map[string]string)
+// getValue looks up path in the tree of method, appending matched params to ps.
+// It doesn't allocate when cap(*ps) >= r.maxParams and path is clean.
+func (r *router) getValue(method string, path string, ps *Params) *node {
 	root, ok := r.roots[method]
-
 	if !ok {
-		return nil, nil
+		return nil
 	}
+	return root.search(cleanPath(path), ps)
+}
 
-	n := root.search(searchParts, 0)
-
-	if n != nil {
-		parts := parsePattern(n.pattern)
-		for index, part := range parts {
-			if part[0] == ':' {
-				params[part[1:]] = searchParts[index]
-			}
-			if part[0] == '*' && len(part) > 1 {
-				params[part[1:]] = strings.Join(searchParts[index:], "/")
-				break
-			}
+// cleanPath collapses repeated slashes and drops a trailing one, so that
+// "/hello/geektutu/" and "//hello/geektutu" match "/hello/:name" as empty
+// segments are ignored. It only allocates when path needs cleaning.
+func cleanPath(path string) string {
+	clean := true
+	for i := 1; i < len(path); i++ {
+		if path[i] == '/' && (path[i-1] == '/' || i == len(path)-1) {
+			clean = false
+			break
+		}
+	}
+	if clean {
+		return path
+	}
+	b := make([]byte, 0, len(path))
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && len(b) > 0 && b[len(b)-1] == '/' {
+			continue
 		}
-		return n, params
+		b = append(b, path[i])
 	}
+	if len(b) > 1 && b[len(b)-1] == '/' {
+		b = b[:len(b)-1]
+	}
+	return string(b)
+}
 
-	return nil, nil
+func (r *router) getRoute(method string, path string) (*node, Params) {
+	params := make(Params, 0, r.maxParams)
+	n := r.getValue(method, path, &params)
+	if n == nil {
+		return nil, nil
+	}
+	return n, params
 }
 
 func (r *router) getRoutes(method string) []*node {
@@ -83,17 +164,67 @@ func (r *router) getRoutes(method string) []*node {
 	return nodes
 }
 
-func (r *router) handle(c *Context) {
-	n, params := r.getRoute(c.Method, c.Path)
+// allowed returns the sorted methods whose tries of routers match path,
+// OPTIONS is always included when anything matches since it is answered
+// automatically, or when path has an explicit OPTIONS route
+func allowed(path string, routers ...*router) []string {
+	methods := make([]string, 0)
+	for _, r := range routers {
+		if r == nil {
+			continue
+		}
+	next:
+		for method := range r.roots {
+			for _, m := range methods {
+				if m == method {
+					continue next
+				}
+			}
+			var ps Params
+			if n := r.getValue(method, path, &ps); n != nil {
+				methods = append(methods, method)
+			}
+		}
+	}
+	if len(methods) == 0 {
+		return nil
+	}
+	hasOptions := false
+	for _, m := range methods {
+		hasOptions = hasOptions || m == "OPTIONS"
+	}
+	if !hasOptions {
+		methods = append(methods, "OPTIONS")
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// handle runs the route of c, looking up the routes of the request host
+// before the default ones
+func (engine *Engine) handle(c *Context) {
+	c.Params = c.Params[:0]
+	host := engine.matchHost(c)
+	var n *node
+	if host != nil {
+		n = host.getValue(c.Method, c.Path, &c.Params)
+	}
+	if n == nil {
+		n = engine.router.getValue(c.Method, c.Path, &c.Params)
+	}
 
 	if n != nil {
-		key := c.Method + "-" + n.pattern
-		c.Params = params
-		c.handlers = append(c.handlers, r.handlers[key])
+		c.handlers = n.handlers
+		c.fullPath = n.pattern
+	} else if allow := allowed(c.Path, host, engine.router); allow != nil {
+		c.SetHeader("Allow", strings.Join(allow, ", "))
+		if c.Method == "OPTIONS" {
+			c.handlers = engine.allOptions
+		} else {
+			c.handlers = engine.allNoMethod
+		}
 	} else {
-		c.handlers = append(c.handlers, func(c *Context) {
-			c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
-		})
+		c.handlers = engine.allNoRoute
 	}
 	c.Next()
 }
@@ -2,6 +2,8 @@ package gee
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 )
@@ -37,24 +39,48 @@ func TestGetRoute(t *testing.T) {
 		t.Fatal("should match /hello/:name")
 	}
 
-	if ps["name"] != "geektutu" {
+	if ps.ByName("name") != "geektutu" {
 		t.Fatal("name should be equal to 'geektutu'")
 	}
 
-	fmt.Printf("matched path: %s, params['name']: %s\n", n.pattern, ps["name"])
+	fmt.Printf("matched path: %s, params['name']: %s\n", n.pattern, ps.ByName("name"))
 
 }
 
+func TestGetRouteEmptySegments(t *testing.T) {
+	r := newTestRouter()
+	r.addRoute("GET", "/docs/", nil)
+	cases := []struct {
+		path, pattern, name string
+	}{
+		{"/hello/geektutu/", "/hello/:name", "geektutu"},
+		{"//hello//geektutu", "/hello/:name", "geektutu"},
+		{"/hello/b/c/", "/hello/b/c", ""},
+		{"/docs", "/docs", ""},
+		{"/docs/", "/docs", ""},
+		{"//", "/", ""},
+	}
+	for _, c := range cases {
+		n, ps := r.getRoute("GET", c.path)
+		if n == nil || n.pattern != c.pattern || ps.ByName("name") != c.name {
+			t.Fatalf("%s should match %s, got %v %v", c.path, c.pattern, n, ps)
+		}
+	}
+	if n, ps := r.getRoute("GET", "/assets/css/"); n == nil || ps.ByName("filepath") != "css" {
+		t.Fatalf("/assets/css/ should match the catch-all with css, got %v %v", n, ps)
+	}
+}
+
 func TestGetRoute2(t *testing.T) {
 	r := newTestRouter()
 	n1, ps1 := r.getRoute("GET", "/assets/file1.txt")
-	ok1 := n1.pattern == "/assets/*filepath" && ps1["filepath"] == "file1.txt"
+	ok1 := n1.pattern == "/assets/*filepath" && ps1.ByName("filepath") == "file1.txt"
 	if !ok1 {
 		t.Fatal("pattern shoule be /assets/*filepath & filepath shoule be file1.txt")
 	}
 
 	n2, ps2 := r.getRoute("GET", "/assets/css/test.css")
-	ok2 := n2.pattern == "/assets/*filepath" && ps2["filepath"] == "css/test.css"
+	ok2 := n2.pattern == "/assets/*filepath" && ps2.ByName("filepath") == "css/test.css"
 	if !ok2 {
 		t.Fatal("pattern shoule be /assets/*filepath & filepath shoule be css/test.css")
 	}
@@ -72,3 +98,167 @@ func TestGetRoutes(t *testing.T) {
 		t.Fatal("the number of routes shoule be 4")
 	}
 }
+
+func TestMethodNotAllowed(t *testing.T) {
+	r := New()
+	r.GET("/hello/:name", func(c *Context) {})
+	r.PUT("/hello/:name", func(c *Context) {})
+	r.DELETE("/assets/*filepath", func(c *Context) {})
+	r.OPTIONS("/preflight", func(c *Context) {})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/hello/geektutu", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status should be 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, OPTIONS, PUT" {
+		t.Fatalf("Allow should be 'GET, OPTIONS, PUT', got %q", allow)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/assets/css/test.css", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") != "DELETE, OPTIONS" {
+		t.Fatalf("OPTIONS should be answered automatically, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/preflight", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "OPTIONS" {
+		t.Fatalf("a path with an OPTIONS route only should be 405 with Allow OPTIONS, got %d %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/unknown", nil))
+	if w.Code != http.StatusNotFound || w.Header().Get("Allow") != "" {
+		t.Fatalf("unknown path should be 404 without Allow, got %d", w.Code)
+	}
+}
+
+func TestAny(t *testing.T) {
+	r := New()
+	r.Any("/any", func(c *Context) {
+		c.String(http.StatusOK, c.Method)
+	})
+	for _, method := range anyMethods {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, "/any", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s /any should be 200, got %d", method, w.Code)
+		}
+	}
+}
+
+func TestRoutePriority(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/assets/*filepath", nil)
+	r.addRoute("GET", "/assets/logo", nil)
+	r.addRoute("GET", "/p/:lang/doc", nil)
+	r.addRoute("GET", "/p/go/:page", nil)
+	r.addRoute("GET", "/p/go/intro", nil)
+
+	cases := []struct {
+		path, pattern string
+	}{
+		{"/assets/logo", "/assets/logo"},
+		{"/assets/logo.png", "/assets/*filepath"},
+		{"/assets/css/logo", "/assets/*filepath"},
+		{"/p/go/intro", "/p/go/intro"},
+		{"/p/go/tour", "/p/go/:page"},
+		{"/p/go/doc", "/p/go/:page"},
+		{"/p/c/doc", "/p/:lang/doc"},
+	}
+	for _, c := range cases {
+		n, _ := r.getRoute("GET", c.path)
+		if n == nil || n.pattern != c.pattern {
+			t.Fatalf("%s should match %s, got %v", c.path, c.pattern, n)
+		}
+	}
+
+	if n, _ := r.getRoute("GET", "/p/go/doc/x"); n != nil {
+		t.Fatalf("/p/go/doc/x shouldn't match, got %s", n.pattern)
+	}
+}
+
+func TestRouteBacktrack(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/p/go/intro", nil)
+	r.addRoute("GET", "/p/:lang/doc", nil)
+	n, ps := r.getRoute("GET", "/p/go/doc")
+	if n == nil || n.pattern != "/p/:lang/doc" || ps.ByName("lang") != "go" {
+		t.Fatal("/p/go/doc should fall back to /p/:lang/doc")
+	}
+}
+
+func TestRouteConstraints(t *testing.T) {
+	r := newRouter()
+	r.addRoute("GET", "/user/{id:int}", nil)
+	r.addRoute("GET", "/user/{name}", nil)
+	r.addRoute("GET", "/user/{id:int}/posts", nil)
+	r.addRoute("GET", "/file/{name:[a-z]+\\.txt}", nil)
+	r.addRoute("GET", "/file/:name/raw", nil)
+	r.addRoute("GET", "/v/{uuid:uuid}", nil)
+	r.addRoute("GET", "/v/{version:v[0-9]+}", nil)
+	r.addRoute("GET", "/v/*rest", nil)
+
+	cases := []struct {
+		path, pattern, key, value string
+	}{
+		{"/user/42", "/user/{id:int}", "id", "42"},
+		{"/user/-7", "/user/{id:int}", "id", "-7"},
+		{"/user/geektutu", "/user/{name}", "name", "geektutu"},
+		{"/user/42/posts", "/user/{id:int}/posts", "id", "42"},
+		{"/file/notes.txt", "/file/{name:[a-z]+\\.txt}", "name", "notes.txt"},
+		{"/file/notes.txt/raw", "/file/:name/raw", "name", "notes.txt"},
+		{"/v/6ba7b810-9dad-11d1-80B4-00c04fd430c8", "/v/{uuid:uuid}", "uuid", "6ba7b810-9dad-11d1-80B4-00c04fd430c8"},
+		{"/v/v2", "/v/{version:v[0-9]+}", "version", "v2"},
+		{"/v/v2x", "/v/*rest", "rest", "v2x"},
+		{"/v/6ba7b810-9dad-11d1-80b4-00c04fd430c", "/v/*rest", "rest", "6ba7b810-9dad-11d1-80b4-00c04fd430c"},
+	}
+	for _, c := range cases {
+		n, ps := r.getRoute("GET", c.path)
+		if n == nil || n.pattern != c.pattern || ps.ByName(c.key) != c.value {
+			t.Fatalf("%s should match %s with %s=%s, got %v %v", c.path, c.pattern, c.key, c.value, n, ps)
+		}
+	}
+
+	for _, path := range []string{"/user/geektutu/posts", "/file/Notes.txt", "/file/notes.md"} {
+		if n, _ := r.getRoute("GET", path); n != nil {
+			t.Fatalf("%s shouldn't match, got %s", path, n.pattern)
+		}
+	}
+}
+
+func TestRouteConflicts(t *testing.T) {
+	cases := []struct {
+		name     string
+		existing []string
+		pattern  string
+	}{
+		{"param name", []string{"/p/:name/x"}, "/p/:lang/doc"},
+		{"catch-all name", []string{"/assets/*filepath"}, "/assets/*path"},
+		{"duplicate", []string{"/hello/:name"}, "/hello/:name"},
+		{"after catch-all", nil, "/assets/*filepath/x"},
+		{"unnamed param", nil, "/p/:/x"},
+		{"no leading slash", nil, "hello"},
+		{"constraint name", []string{"/user/{id:int}"}, "/user/{uid:int}"},
+		{"param and braces", []string{"/user/:id"}, "/user/{uid}"},
+		{"invalid regexp", nil, "/user/{id:[0-9}"},
+		{"unclosed brace", nil, "/file/{name:[a-z]+/x}"},
+		{"empty constraint", nil, "/user/{id:}"},
+		{"unnamed constraint", nil, "/user/{:int}"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := newRouter()
+			for _, pattern := range c.existing {
+				r.addRoute("GET", pattern, nil)
+			}
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("adding %s should panic", c.pattern)
+				}
+			}()
+			r.addRoute("GET", c.pattern, nil)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package gee
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo describes a registered route
+type RouteInfo struct {
+	Host        string `json:"host,omitempty"` // host pattern of routes added by Engine.Host
+	Method      string `json:"method"`
+	Pattern     string `json:"pattern"`
+	Name        string `json:"name,omitempty"`
+	Handler     string `json:"handler"`     // function name of the final handler
+	Middlewares int    `json:"middlewares"` // number of handlers before it
+}
+
+// Routes returns every registered route, sorted by host, pattern then method
+func (engine *Engine) Routes() []RouteInfo {
+	routes := engine.router.routeInfos("", make([]RouteInfo, 0))
+	for _, hr := range engine.hostRouters {
+		routes = hr.router.routeInfos(hr.pattern, routes)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Host != routes[j].Host {
+			return routes[i].Host < routes[j].Host
+		}
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// routeInfos appends the routes of r for host to routes
+func (r *router) routeInfos(host string, routes []RouteInfo) []RouteInfo {
+	for method := range r.roots {
+		for _, n := range r.getRoutes(method) {
+			routes = append(routes, RouteInfo{
+				Host:        host,
+				Method:      method,
+				Pattern:     n.pattern,
+				Name:        n.name,
+				Handler:     nameOfFunction(n.handlers[len(n.handlers)-1]),
+				Middlewares: len(n.handlers) - 1,
+			})
+		}
+	}
+	return routes
+}
+
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+// writeRoutes writes the routes as an aligned text table, host routes have
+// their host before the pattern
+func writeRoutes(w io.Writer, routes []RouteInfo) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATTERN\tNAME\tHANDLER\tMIDDLEWARES")
+	for _, r := range routes {
+		name := r.Name
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\n", r.Method, r.Host+r.Pattern, name, r.Handler, r.Middlewares)
+	}
+	tw.Flush()
+}
+
+// logRoutes prints the route table when the server starts
+func (engine *Engine) logRoutes() {
+	var buf bytes.Buffer
+	writeRoutes(&buf, engine.Routes())
+	log.Printf("Routes:\n%s", buf.String())
+}
+
+var routesTemplate = template.Must(template.New("routes").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Routes</title></head>
+<body>
+<table>
+<tr><th>Method</th><th>Pattern</th><th>Name</th><th>Handler</th><th>Middlewares</th></tr>
+{{range .}}<tr><td>{{.Method}}</td><td>{{.Host}}{{.Pattern}}</td><td>{{.Name}}</td><td>{{.Handler}}</td><td>{{.Middlewares}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// DebugRoutes returns a handler listing the routes of engine, as an HTML table
+// for browsers and JSON otherwise, e.g. r.GET("/debug/routes", r.DebugRoutes())
+func (engine *Engine) DebugRoutes() HandlerFunc {
+	return func(c *Context) {
+		routes := engine.Routes()
+		if strings.Contains(c.Req.Header.Get("Accept"), "text/html") {
+			c.SetHeader("Content-Type", "text/html; charset=utf-8")
+			c.Status(http.StatusOK)
+			if err := routesTemplate.Execute(c.Writer, routes); err != nil {
+				c.Fail(http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+		c.JSON(http.StatusOK, routes)
+	}
+}
@@ -0,0 +1,59 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func helloHandler(c *Context) {}
+
+func TestRoutes(t *testing.T) {
+	r := New()
+	r.Use(Logger())
+	r.GET("/hello/:name", helloHandler).Name("hello")
+	r.POST("/hello/:name", Recovery(), helloHandler)
+	r.GET("/debug/routes", r.DebugRoutes())
+
+	routes := r.Routes()
+	if len(routes) != 3 {
+		t.Fatalf("should list 3 routes, got %d", len(routes))
+	}
+	get, post := routes[1], routes[2]
+	if get.Method != "GET" || get.Pattern != "/hello/:name" || get.Name != "hello" ||
+		get.Handler != "gee.helloHandler" || get.Middlewares != 1 {
+		t.Fatalf("unexpected route %+v", get)
+	}
+	if post.Method != "POST" || post.Middlewares != 2 {
+		t.Fatalf("unexpected route %+v", post)
+	}
+
+	var buf bytes.Buffer
+	writeRoutes(&buf, routes)
+	if !strings.Contains(buf.String(), "GET     /hello/:name   hello") {
+		t.Fatalf("unexpected route table\n%s", buf.String())
+	}
+}
+
+func TestDebugRoutes(t *testing.T) {
+	r := New()
+	r.GET("/hello/:name", helloHandler)
+	r.GET("/debug/routes", r.DebugRoutes())
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/debug/routes", nil))
+	var routes []RouteInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &routes); err != nil || len(routes) != 2 {
+		t.Fatalf("should list routes as JSON, got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/debug/routes", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	r.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), "<td>/hello/:name</td>") {
+		t.Fatalf("should list routes as HTML, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,166 @@
+package gee
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ServerConfig holds the settings of the http.Server started by the Run* methods
+type ServerConfig struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	TLSConfig         *tls.Config
+	// HandleSignals shuts the server down gracefully on SIGINT and SIGTERM,
+	// Run* then returns once in-flight requests are drained
+	HandleSignals bool
+	// ShutdownTimeout bounds the drain triggered by a signal, 0 waits forever
+	ShutdownTimeout time.Duration
+}
+
+// Server applies cfg and returns the http.Server shared by the Run* methods,
+// it must be called before them. The returned server may be customized further.
+func (engine *Engine) Server(cfg ServerConfig) *http.Server {
+	engine.serverMu.Lock()
+	defer engine.serverMu.Unlock()
+	engine.config = cfg
+	engine.server = engine.newServer()
+	return engine.server
+}
+
+func (engine *Engine) newServer() *http.Server {
+	cfg := engine.config
+	return &http.Server{
+		Handler:           engine,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		TLSConfig:         cfg.TLSConfig,
+	}
+}
+
+func (engine *Engine) getServer() *http.Server {
+	engine.serverMu.Lock()
+	defer engine.serverMu.Unlock()
+	if engine.server == nil {
+		engine.server = engine.newServer()
+	}
+	return engine.server
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests to
+// finish or ctx to be done, Run* then return nil. A later Run* call starts
+// a new server from the ServerConfig.
+func (engine *Engine) Shutdown(ctx context.Context) error {
+	engine.serverMu.Lock()
+	srv := engine.server
+	engine.server = nil
+	engine.serverMu.Unlock()
+	if srv == nil {
+		return nil
+	}
+	return srv.Shutdown(ctx)
+}
+
+// shutdownServer shuts srv down, forgetting it when it is the engine server
+func (engine *Engine) shutdownServer(ctx context.Context, srv *http.Server) error {
+	engine.serverMu.Lock()
+	if engine.server == srv {
+		engine.server = nil
+	}
+	engine.serverMu.Unlock()
+	return srv.Shutdown(ctx)
+}
+
+// Run defines the method to start a http server
+func (engine *Engine) Run(addr string) (err error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return engine.RunListener(l)
+}
+
+// RunTLS starts a https server with the certificate and key files,
+// they may be empty when ServerConfig.TLSConfig provides certificates
+func (engine *Engine) RunTLS(addr string, certFile string, keyFile string) (err error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := engine.getServer()
+	return engine.serve(srv, func() error {
+		return srv.ServeTLS(l, certFile, keyFile)
+	})
+}
+
+// RunUnix starts a http server listening on the unix socket file
+func (engine *Engine) RunUnix(file string) (err error) {
+	l, err := net.Listen("unix", file)
+	if err != nil {
+		return err
+	}
+	return engine.RunListener(l)
+}
+
+// RunListener starts a http server accepting connections from l
+func (engine *Engine) RunListener(l net.Listener) (err error) {
+	srv := engine.getServer()
+	return engine.serve(srv, func() error {
+		return srv.Serve(l)
+	})
+}
+
+// serve runs serve until the server is closed, shutting it down on
+// SIGINT/SIGTERM when enabled
+func (engine *Engine) serve(srv *http.Server, serve func() error) error {
+	engine.logRoutes()
+	if !engine.config.HandleSignals {
+		return ignoreServerClosed(serve())
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	served := make(chan struct{})
+	shutdown := make(chan error, 1)
+	go func() {
+		defer signal.Stop(sigs)
+		select {
+		case <-sigs:
+			ctx := context.Background()
+			if timeout := engine.config.ShutdownTimeout; timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			shutdown <- engine.shutdownServer(ctx, srv)
+		case <-served:
+			shutdown <- nil
+		}
+	}()
+
+	err := serve()
+	close(served)
+	// Serve returns as soon as Shutdown starts, wait for the drain to finish
+	if shutdownErr := <-shutdown; err == http.ErrServerClosed {
+		return shutdownErr
+	}
+	return err
+}
+
+func ignoreServerClosed(err error) error {
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,79 @@
+package gee
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	r := New()
+	started := make(chan struct{})
+	r.GET("/slow", func(c *Context) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+	r.GET("/ping", func(c *Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	srv := r.Server(ServerConfig{ReadTimeout: time.Second, MaxHeaderBytes: 1 << 16})
+	if srv.ReadTimeout != time.Second || srv.MaxHeaderBytes != 1<<16 {
+		t.Fatal("server should be configured from ServerConfig")
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- r.RunListener(l)
+	}()
+
+	body := make(chan string, 1)
+	go func() {
+		res, err := http.Get("http://" + l.Addr().String() + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		body <- string(b)
+	}()
+
+	<-started
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if b := <-body; b != "done" {
+		t.Fatalf("in-flight request should be drained, got %q", b)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("Run should return nil after Shutdown, got %v", err)
+	}
+
+	// the server can be started again after a shutdown
+	l, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		runErr <- r.RunListener(l)
+	}()
+	res, err := http.Get("http://" + l.Addr().String() + "/ping")
+	if err != nil {
+		t.Fatalf("server should serve again after Shutdown, got %v", err)
+	}
+	res.Body.Close()
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("Run should return nil after Shutdown, got %v", err)
+	}
+}
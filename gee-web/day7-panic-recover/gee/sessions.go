@@ -0,0 +1,441 @@
+package gee
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sessionKey = "gee/session"
+
+// maxCookieSize is the largest cookie value browsers are guaranteed to keep
+const maxCookieSize = 4096
+
+var (
+	// ErrInvalidSession is returned when a session cookie fails verification
+	ErrInvalidSession = errors.New("gee: invalid session cookie")
+	// ErrSessionExpired is returned when a session cookie is older than MaxAge
+	ErrSessionExpired = errors.New("gee: session cookie expired")
+)
+
+// SessionOptions are the attributes of the session cookie
+type SessionOptions struct {
+	Path     string
+	Domain   string
+	MaxAge   int // seconds, < 0 deletes the session
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+}
+
+func defaultSessionOptions() SessionOptions {
+	return SessionOptions{
+		Path:     "/",
+		MaxAge:   86400 * 30,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// Session holds the values of one client, stored by a Store.
+// Values must be gob encodable, register custom types with gob.Register.
+type Session struct {
+	ID      string // id in the Store, empty for stores keeping values in the cookie
+	Name    string // cookie name
+	Values  map[string]interface{}
+	Options SessionOptions
+	IsNew   bool
+
+	store Store
+	ctx   *Context
+}
+
+// NewSession returns an empty session, used by Store implementations
+func NewSession(store Store, name string, options SessionOptions) *Session {
+	return &Session{
+		Name:    name,
+		Values:  make(map[string]interface{}),
+		Options: options,
+		IsNew:   true,
+		store:   store,
+	}
+}
+
+// Get returns the value of key, nil if there is none
+func (s *Session) Get(key string) interface{} {
+	return s.Values[key]
+}
+
+// Set sets the value of key, Save must be called to keep it
+func (s *Session) Set(key string, value interface{}) {
+	s.Values[key] = value
+}
+
+// Delete removes key, Save must be called to keep the change
+func (s *Session) Delete(key string) {
+	delete(s.Values, key)
+}
+
+// Clear removes every value, Save must be called to keep the change
+func (s *Session) Clear() {
+	s.Values = make(map[string]interface{})
+}
+
+// Destroy clears the session and deletes it from the store and the client on Save
+func (s *Session) Destroy() {
+	s.Clear()
+	s.Options.MaxAge = -1
+}
+
+// Save persists the session and sets its cookie,
+// it must be called before the response body is written
+func (s *Session) Save() error {
+	return s.store.Save(s.ctx, s)
+}
+
+// Store loads and saves sessions
+type Store interface {
+	// Load returns the session name of the request,
+	// a new one if there is none or it can't be verified
+	Load(c *Context, name string) (*Session, error)
+	// Save persists s and writes its cookie to the response
+	Save(c *Context, s *Session) error
+}
+
+// Sessions loads the session name from store for every request,
+// handlers get it with c.Session()
+func Sessions(name string, store Store) HandlerFunc {
+	return func(c *Context) {
+		s, err := store.Load(c, name)
+		if err != nil && s == nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		s.ctx = c
+		c.Set(sessionKey, s)
+		c.Next()
+	}
+}
+
+// Session returns the session loaded by the Sessions middleware, bound to c
+// so that Save writes the cookie to the response c is writing, such as the
+// buffered one of Timeout
+func (c *Context) Session() *Session {
+	s := c.MustGet(sessionKey).(*Session)
+	s.ctx = c
+	return s
+}
+
+func setSessionCookie(c *Context, s *Session, value string) {
+	opts := s.Options
+	cookie := &http.Cookie{
+		Name:     s.Name,
+		Value:    value,
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		MaxAge:   opts.MaxAge,
+		Secure:   opts.Secure,
+		HttpOnly: opts.HttpOnly,
+		SameSite: opts.SameSite,
+	}
+	if opts.MaxAge > 0 {
+		cookie.Expires = time.Now().Add(time.Duration(opts.MaxAge) * time.Second)
+	} else if opts.MaxAge < 0 {
+		cookie.Value = ""
+		cookie.Expires = time.Unix(1, 0)
+	}
+	http.SetCookie(c.Writer, cookie)
+}
+
+func encodeValues(values map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeValues(b []byte) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// cookieCodec signs and optionally encrypts cookie values
+type cookieCodec struct {
+	hashKey []byte
+	aead    cipher.AEAD // nil when encryption is disabled
+}
+
+func newCookieCodec(hashKey, blockKey []byte) (*cookieCodec, error) {
+	if len(hashKey) == 0 {
+		return nil, errors.New("gee: hash key must not be empty")
+	}
+	codec := &cookieCodec{hashKey: hashKey}
+	if len(blockKey) > 0 {
+		block, err := aes.NewCipher(blockKey)
+		if err != nil {
+			return nil, fmt.Errorf("gee: invalid block key: %v", err)
+		}
+		if codec.aead, err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+	return codec, nil
+}
+
+func (codec *cookieCodec) mac(name, body string) []byte {
+	h := hmac.New(sha256.New, codec.hashKey)
+	h.Write([]byte(name + "|" + body))
+	return h.Sum(nil)
+}
+
+// encode returns "timestamp|payload|mac", the payload is encrypted with
+// the cookie name as additional data when a block key is set
+func (codec *cookieCodec) encode(name string, value []byte) (string, error) {
+	if codec.aead != nil {
+		nonce := make([]byte, codec.aead.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return "", err
+		}
+		value = codec.aead.Seal(nonce, nonce, value, []byte(name))
+	}
+	body := strconv.FormatInt(time.Now().Unix(), 10) + "|" + base64.RawURLEncoding.EncodeToString(value)
+	return body + "|" + base64.RawURLEncoding.EncodeToString(codec.mac(name, body)), nil
+}
+
+// decode verifies and decrypts a value made by encode, rejecting it when
+// it is older than maxAge seconds (maxAge <= 0 disables the check)
+func (codec *cookieCodec) decode(name string, cookie string, maxAge int) ([]byte, error) {
+	i := strings.LastIndexByte(cookie, '|')
+	if i < 0 {
+		return nil, ErrInvalidSession
+	}
+	body := cookie[:i]
+	mac, err := base64.RawURLEncoding.DecodeString(cookie[i+1:])
+	if err != nil || !hmac.Equal(mac, codec.mac(name, body)) {
+		return nil, ErrInvalidSession
+	}
+
+	parts := strings.SplitN(body, "|", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidSession
+	}
+	ts, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+	if maxAge > 0 && time.Now().Unix()-ts > int64(maxAge) {
+		return nil, ErrSessionExpired
+	}
+	value, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+
+	if codec.aead != nil {
+		size := codec.aead.NonceSize()
+		if len(value) < size {
+			return nil, ErrInvalidSession
+		}
+		if value, err = codec.aead.Open(nil, value[:size], value[size:], []byte(name)); err != nil {
+			return nil, ErrInvalidSession
+		}
+	}
+	return value, nil
+}
+
+func newCookieCodecs(keyPairs [][]byte) []*cookieCodec {
+	if len(keyPairs) == 0 {
+		panic("gee: at least one hash key is required")
+	}
+	codecs := make([]*cookieCodec, 0, (len(keyPairs)+1)/2)
+	for i := 0; i < len(keyPairs); i += 2 {
+		var blockKey []byte
+		if i+1 < len(keyPairs) {
+			blockKey = keyPairs[i+1]
+		}
+		codec, err := newCookieCodec(keyPairs[i], blockKey)
+		if err != nil {
+			panic(err)
+		}
+		codecs = append(codecs, codec)
+	}
+	return codecs
+}
+
+// CookieStore keeps the session values in a signed, optionally encrypted cookie
+type CookieStore struct {
+	Options SessionOptions
+	codecs  []*cookieCodec
+}
+
+// NewCookieStore returns a store signing cookies with HMAC-SHA256.
+// keyPairs are hash key and block key pairs, a non-empty block key of 16, 24
+// or 32 bytes enables AES-GCM encryption. The first pair encodes cookies, all
+// pairs are tried when decoding, so keys can be rotated by prepending a new pair.
+func NewCookieStore(keyPairs ...[]byte) *CookieStore {
+	return &CookieStore{Options: defaultSessionOptions(), codecs: newCookieCodecs(keyPairs)}
+}
+
+func decodeCookie(codecs []*cookieCodec, name, value string, maxAge int) ([]byte, error) {
+	err := ErrInvalidSession
+	for _, codec := range codecs {
+		var b []byte
+		if b, err = codec.decode(name, value, maxAge); err == nil {
+			return b, nil
+		}
+	}
+	return nil, err
+}
+
+func (store *CookieStore) Load(c *Context, name string) (*Session, error) {
+	s := NewSession(store, name, store.Options)
+	cookie, err := c.Req.Cookie(name)
+	if err != nil {
+		return s, nil
+	}
+	b, err := decodeCookie(store.codecs, name, cookie.Value, store.Options.MaxAge)
+	if err != nil {
+		return s, err
+	}
+	values, err := decodeValues(b)
+	if err != nil {
+		return s, ErrInvalidSession
+	}
+	s.Values, s.IsNew = values, false
+	return s, nil
+}
+
+func (store *CookieStore) Save(c *Context, s *Session) error {
+	if s.Options.MaxAge < 0 {
+		setSessionCookie(c, s, "")
+		return nil
+	}
+	b, err := encodeValues(s.Values)
+	if err != nil {
+		return err
+	}
+	value, err := store.codecs[0].encode(s.Name, b)
+	if err != nil {
+		return err
+	}
+	if len(value) > maxCookieSize {
+		return fmt.Errorf("gee: session cookie is %d bytes, over the %d limit", len(value), maxCookieSize)
+	}
+	setSessionCookie(c, s, value)
+	return nil
+}
+
+type memorySession struct {
+	values  []byte // gob encoded, so stored sessions don't share maps with handlers
+	expires time.Time
+}
+
+// MemoryStore keeps the session values in process memory, the cookie only holds
+// a random session id. Expired sessions are evicted as they are found.
+type MemoryStore struct {
+	Options  SessionOptions
+	mu       sync.Mutex
+	sessions map[string]memorySession
+	saves    int
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{Options: defaultSessionOptions(), sessions: make(map[string]memorySession)}
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (store *MemoryStore) Load(c *Context, name string) (*Session, error) {
+	s := NewSession(store, name, store.Options)
+	cookie, err := c.Req.Cookie(name)
+	if err != nil {
+		return s, nil
+	}
+
+	store.mu.Lock()
+	ms, ok := store.sessions[cookie.Value]
+	if ok && time.Now().After(ms.expires) {
+		delete(store.sessions, cookie.Value)
+		ok = false
+	}
+	store.mu.Unlock()
+	if !ok {
+		return s, nil
+	}
+
+	values, err := decodeValues(ms.values)
+	if err != nil {
+		return s, err
+	}
+	s.ID, s.Values, s.IsNew = cookie.Value, values, false
+	return s, nil
+}
+
+func (store *MemoryStore) Save(c *Context, s *Session) error {
+	if s.Options.MaxAge < 0 {
+		store.mu.Lock()
+		delete(store.sessions, s.ID)
+		store.mu.Unlock()
+		setSessionCookie(c, s, "")
+		return nil
+	}
+
+	b, err := encodeValues(s.Values)
+	if err != nil {
+		return err
+	}
+	if s.ID == "" {
+		if s.ID, err = newSessionID(); err != nil {
+			return err
+		}
+	}
+	maxAge := s.Options.MaxAge
+	if maxAge == 0 {
+		maxAge = defaultSessionOptions().MaxAge
+	}
+
+	store.mu.Lock()
+	store.sessions[s.ID] = memorySession{values: b, expires: time.Now().Add(time.Duration(maxAge) * time.Second)}
+	store.saves++
+	if store.saves%1000 == 0 {
+		store.evictLocked()
+	}
+	store.mu.Unlock()
+
+	setSessionCookie(c, s, s.ID)
+	return nil
+}
+
+// evictLocked drops expired sessions, the caller must hold store.mu
+func (store *MemoryStore) evictLocked() {
+	now := time.Now()
+	for id, ms := range store.sessions {
+		if now.After(ms.expires) {
+			delete(store.sessions, id)
+		}
+	}
+}
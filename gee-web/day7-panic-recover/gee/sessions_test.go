@@ -0,0 +1,169 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// sessionRequest runs req through r, sending cookies and returning the response cookies
+func sessionRequest(r *Engine, path string, cookies []*http.Cookie) (*httptest.ResponseRecorder, []*http.Cookie) {
+	req := httptest.NewRequest("GET", path, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w, w.Result().Cookies()
+}
+
+func counterEngine(store Store) *Engine {
+	r := New()
+	r.Use(Sessions("gee", store))
+	r.GET("/count", func(c *Context) {
+		s := c.Session()
+		n, _ := s.Get("n").(int)
+		s.Set("n", n+1)
+		if err := s.Save(); err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "%d", n+1)
+	})
+	r.GET("/logout", func(c *Context) {
+		s := c.Session()
+		s.Destroy()
+		s.Save()
+		c.Status(http.StatusNoContent)
+	})
+	return r
+}
+
+func TestCookie(t *testing.T) {
+	r := New()
+	r.GET("/set", func(c *Context) {
+		c.SetCookie("user", "gee tutu", 60, "", "", false, true)
+	})
+	r.GET("/get", func(c *Context) {
+		v, err := c.Cookie("user")
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.String(http.StatusOK, v)
+	})
+
+	_, cookies := sessionRequest(r, "/set", nil)
+	if len(cookies) != 1 || !cookies[0].HttpOnly || cookies[0].Path != "/" || cookies[0].MaxAge != 60 {
+		t.Fatalf("unexpected cookies %v", cookies)
+	}
+	if w, _ := sessionRequest(r, "/get", cookies); w.Body.String() != "gee tutu" {
+		t.Fatalf("cookie should round trip, got %q", w.Body.String())
+	}
+	if w, _ := sessionRequest(r, "/get", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("missing cookie should fail, got %d", w.Code)
+	}
+}
+
+func TestCookieStore(t *testing.T) {
+	cases := map[string]*CookieStore{
+		"signed":    NewCookieStore([]byte("hash-key")),
+		"encrypted": NewCookieStore([]byte("hash-key"), []byte("0123456789abcdef")),
+	}
+	for name, store := range cases {
+		r := counterEngine(store)
+		_, cookies := sessionRequest(r, "/count", nil)
+		w, cookies := sessionRequest(r, "/count", cookies)
+		if w.Body.String() != "2" {
+			t.Fatalf("%s: session should keep values, got %s", name, w.Body.String())
+		}
+
+		tampered := *cookies[0]
+		tampered.Value = "1" + tampered.Value
+		if w, _ := sessionRequest(r, "/count", []*http.Cookie{&tampered}); w.Body.String() != "1" {
+			t.Fatalf("%s: tampered cookie should start a new session, got %s", name, w.Body.String())
+		}
+
+		_, cookies = sessionRequest(r, "/logout", cookies)
+		if len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+			t.Fatalf("%s: Destroy should delete the cookie, got %v", name, cookies)
+		}
+	}
+
+	encrypted := cases["encrypted"]
+	_, cookies := sessionRequest(counterEngine(encrypted), "/count", nil)
+	if strings.Contains(cookies[0].Value, "int") {
+		t.Fatal("encrypted cookie should not expose gob type names")
+	}
+}
+
+func TestCookieStoreKeyRotation(t *testing.T) {
+	old := NewCookieStore([]byte("old-key"))
+	_, cookies := sessionRequest(counterEngine(old), "/count", nil)
+
+	rotated := NewCookieStore([]byte("new-key"), nil, []byte("old-key"), nil)
+	w, newCookies := sessionRequest(counterEngine(rotated), "/count", cookies)
+	if w.Body.String() != "2" {
+		t.Fatalf("old key should still decode, got %s", w.Body.String())
+	}
+
+	if w, _ := sessionRequest(counterEngine(NewCookieStore([]byte("new-key"))), "/count", newCookies); w.Body.String() != "3" {
+		t.Fatalf("cookie should be re-signed with the new key, got %s", w.Body.String())
+	}
+	if w, _ := sessionRequest(counterEngine(NewCookieStore([]byte("other-key"))), "/count", cookies); w.Body.String() != "1" {
+		t.Fatalf("unknown key should be rejected, got %s", w.Body.String())
+	}
+}
+
+func TestCookieStoreLimits(t *testing.T) {
+	codec, _ := newCookieCodec([]byte("hash-key"), nil)
+	value, _ := codec.encode("gee", []byte("payload"))
+	if _, err := codec.decode("other", value, 0); err != ErrInvalidSession {
+		t.Fatalf("cookie name should be signed, got %v", err)
+	}
+	ts := value[:strings.IndexByte(value, '|')]
+	old := "1" + value[len(ts):]
+	if _, err := codec.decode("gee", old, 60); err != ErrInvalidSession {
+		t.Fatalf("changed timestamp should break the signature, got %v", err)
+	}
+
+	store := NewCookieStore([]byte("hash-key"))
+	r := New()
+	r.Use(Sessions("gee", store))
+	r.GET("/big", func(c *Context) {
+		c.Session().Set("big", strings.Repeat("x", maxCookieSize))
+		if err := c.Session().Save(); err == nil {
+			t.Error("oversized cookie should fail")
+		}
+	})
+	sessionRequest(r, "/big", nil)
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	r := counterEngine(store)
+	_, cookies := sessionRequest(r, "/count", nil)
+	if w, _ := sessionRequest(r, "/count", cookies); w.Body.String() != "2" {
+		t.Fatalf("session should keep values, got %s", w.Body.String())
+	}
+	if w, _ := sessionRequest(r, "/count", []*http.Cookie{{Name: "gee", Value: "unknown"}}); w.Body.String() != "1" {
+		t.Fatalf("unknown id should start a new session, got %s", w.Body.String())
+	}
+
+	store.mu.Lock()
+	for id, ms := range store.sessions {
+		ms.expires = ms.expires.AddDate(-1, 0, 0)
+		store.sessions[id] = ms
+	}
+	store.mu.Unlock()
+	if w, _ := sessionRequest(r, "/count", cookies); w.Body.String() != "1" {
+		t.Fatalf("expired session should be dropped, got %s", w.Body.String())
+	}
+
+	_, cookies = sessionRequest(r, "/count", nil)
+	sessionRequest(r, "/logout", cookies)
+	if w, _ := sessionRequest(r, "/count", cookies); w.Body.String() != "1" {
+		t.Fatalf("destroyed session should be removed, got %s", w.Body.String())
+	}
+}
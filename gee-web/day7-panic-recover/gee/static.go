@@ -0,0 +1,257 @@
+package gee
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticConfig configures how StaticWithConfig serves files
+type StaticConfig struct {
+	// Browse lists the directories without an index.html
+	Browse bool
+	// MaxAge is sent as Cache-Control max-age, 0 sends no-cache so that
+	// clients revalidate with the ETag and Last-Modified validators
+	MaxAge time.Duration
+	// Precompressed serves name.gz instead of name to clients accepting gzip
+	Precompressed bool
+	// SPA serves the root index.html for paths matching no file,
+	// for single page applications routing on the client
+	SPA bool
+}
+
+// Static serves the files of the directory root under relativePath,
+// directories are not listed
+func (group *RouterGroup) Static(relativePath string, root string) *Route {
+	return group.StaticFS(relativePath, os.DirFS(root))
+}
+
+// StaticFS serves the files of fsys, such as an embed.FS, under relativePath
+func (group *RouterGroup) StaticFS(relativePath string, fsys fs.FS) *Route {
+	return group.StaticWithConfig(relativePath, fsys, StaticConfig{})
+}
+
+// StaticWithConfig serves the files of fsys under relativePath for GET and
+// HEAD requests, with conditional and range requests support
+func (group *RouterGroup) StaticWithConfig(relativePath string, fsys fs.FS, config StaticConfig) *Route {
+	s := &staticServer{fsys: fsys, config: config}
+	route := group.getAndHead(path.Join(relativePath, "/*filepath"), s.handle)
+	// the catch-all doesn't match an empty path, the root directory gets its own route
+	root := path.Join(relativePath, "/")
+	if root != "/" {
+		root += "/"
+	}
+	route.nodes = append(route.nodes, group.getAndHead(root, s.handle).nodes...)
+	return route
+}
+
+// StaticFile serves the file name of the OS at relativePath
+func (group *RouterGroup) StaticFile(relativePath string, name string) *Route {
+	s := &staticServer{fsys: os.DirFS(filepath.Dir(name))}
+	base := filepath.Base(name)
+	return group.getAndHead(relativePath, func(c *Context) {
+		s.serveName(c, base, true)
+	})
+}
+
+func (group *RouterGroup) getAndHead(pattern string, handler HandlerFunc) *Route {
+	route := group.GET(pattern, handler)
+	route.nodes = append(route.nodes, group.HEAD(pattern, handler).nodes...)
+	return route
+}
+
+type staticServer struct {
+	fsys   fs.FS
+	config StaticConfig
+	etags  sync.Map // name to ETag of files without modification time, as in embed.FS
+}
+
+func (s *staticServer) handle(c *Context) {
+	name := strings.TrimPrefix(path.Clean("/"+c.Param("filepath")), "/")
+	if name == "" {
+		name = "."
+	}
+	s.serveName(c, name, false)
+}
+
+// serveName serves the file or directory name, exact is set for StaticFile
+func (s *staticServer) serveName(c *Context, name string, exact bool) {
+	info, err := fs.Stat(s.fsys, name)
+	if err == nil && info.IsDir() && !exact {
+		if !strings.HasSuffix(c.Req.URL.Path, "/") {
+			http.Redirect(c.Writer, c.Req, path.Base(c.Req.URL.Path)+"/", http.StatusMovedPermanently)
+			return
+		}
+		index := path.Join(name, "index.html")
+		if indexInfo, err := fs.Stat(s.fsys, index); err == nil && !indexInfo.IsDir() {
+			s.serveFile(c, index, indexInfo)
+			return
+		}
+		if s.config.Browse {
+			s.list(c, name)
+			return
+		}
+	} else if err == nil && !info.IsDir() {
+		s.serveFile(c, name, info)
+		return
+	}
+
+	if s.config.SPA && !exact {
+		if indexInfo, err := fs.Stat(s.fsys, "index.html"); err == nil && !indexInfo.IsDir() {
+			s.serveFile(c, "index.html", indexInfo)
+			return
+		}
+	}
+	c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+}
+
+func (s *staticServer) serveFile(c *Context, name string, info fs.FileInfo) {
+	header := c.Writer.Header()
+	if s.config.MaxAge > 0 {
+		header.Set("Cache-Control", "public, max-age="+strconv.FormatInt(int64(s.config.MaxAge/time.Second), 10))
+	} else {
+		header.Set("Cache-Control", "no-cache")
+	}
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		header.Set("Content-Type", ctype)
+	}
+
+	served, suffix := name, ""
+	if s.config.Precompressed {
+		header.Add("Vary", "Accept-Encoding")
+		if gzInfo, err := fs.Stat(s.fsys, name+".gz"); err == nil && !gzInfo.IsDir() && acceptsGzip(c.Req) {
+			header.Set("Content-Encoding", "gzip")
+			served, info, suffix = name+".gz", gzInfo, "-gz"
+		}
+	}
+
+	f, err := s.fsys.Open(served)
+	if err != nil {
+		c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+		return
+	}
+	defer f.Close()
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			failStatic(c, err)
+			return
+		}
+		content = bytes.NewReader(b)
+	}
+	etag, err := s.etag(served, info, content)
+	if err != nil {
+		failStatic(c, err)
+		return
+	}
+	header.Set("ETag", `"`+etag+suffix+`"`)
+	http.ServeContent(c.Writer, c.Req, name, info.ModTime(), content)
+}
+
+// etag derives the ETag of a file from its size and modification time,
+// or from its content when it has no modification time
+func (s *staticServer) etag(name string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	if !info.ModTime().IsZero() {
+		return strconv.FormatInt(info.ModTime().UnixNano(), 36) + "-" + strconv.FormatInt(info.Size(), 36), nil
+	}
+	if etag, ok := s.etags.Load(name); ok {
+		return etag.(string), nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := hex.EncodeToString(h.Sum(nil)[:16])
+	s.etags.Store(name, etag)
+	return etag, nil
+}
+
+var dirListTemplate = template.Must(template.New("dir").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Index of {{.Path}}</title></head>
+<body>
+<h1>Index of {{.Path}}</h1>
+<pre>
+{{range .Entries}}<a href="{{.}}">{{.}}</a>
+{{end}}</pre>
+</body>
+</html>
+`))
+
+func (s *staticServer) list(c *Context, name string) {
+	entries, err := fs.ReadDir(s.fsys, name)
+	if err != nil {
+		failStatic(c, err)
+		return
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name()+"/")
+		} else {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	c.SetHeader("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if c.Method == "HEAD" {
+		return
+	}
+	if err := dirListTemplate.Execute(c.Writer, H{"Path": c.Req.URL.Path, "Entries": names}); err != nil {
+		c.Error(fmt.Errorf("gee: list %s: %v", name, err))
+	}
+}
+
+// failStatic aborts with a 500 for err and renders it, dropping the headers
+// set for the file as nothing was written yet
+func failStatic(c *Context, err error) {
+	c.AbortWithError(http.StatusInternalServerError, err)
+	if !c.Writer.Written() {
+		header := c.Writer.Header()
+		header.Del("Cache-Control")
+		header.Del("Content-Encoding")
+		renderErrors(c)
+	}
+}
+
+// acceptsGzip reports whether the Accept-Encoding of req allows gzip
+func acceptsGzip(req *http.Request) bool {
+	for _, part := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		name, params := part, ""
+		if i := strings.IndexByte(part, ';'); i >= 0 {
+			name, params = part[:i], part[i+1:]
+		}
+		name = strings.TrimSpace(name)
+		if name != "gzip" && name != "*" {
+			continue
+		}
+		params = strings.Replace(params, " ", "", -1)
+		if q := strings.TrimPrefix(params, "q="); q != params {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
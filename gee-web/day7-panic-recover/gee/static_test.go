@@ -0,0 +1,178 @@
+package gee
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func gzipBytes(s string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(s))
+	zw.Close()
+	return buf.Bytes()
+}
+
+func staticRequest(r *Engine, method, path string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+var testFS = fstest.MapFS{
+	"index.html":       {Data: []byte("<h1>app</h1>")},
+	"js/app.js":        {Data: []byte("console.log('gee')")},
+	"js/app.js.gz":     {Data: gzipBytes("console.log('gee')")},
+	"docs/readme.txt":  {Data: []byte("readme")},
+	"docs/guide/a.txt": {Data: []byte("a")},
+}
+
+func TestStaticFS(t *testing.T) {
+	r := New()
+	r.StaticWithConfig("/assets", testFS, StaticConfig{MaxAge: time.Hour, Precompressed: true})
+
+	w := staticRequest(r, "GET", "/assets/js/app.js")
+	if w.Code != http.StatusOK || w.Body.String() != "console.log('gee')" ||
+		!strings.HasPrefix(w.Header().Get("Content-Type"), "text/javascript") ||
+		w.Header().Get("Cache-Control") != "public, max-age=3600" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("unexpected response %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("ETag should be set")
+	}
+	if w := staticRequest(r, "GET", "/assets/js/app.js", "If-None-Match", etag); w.Code != http.StatusNotModified {
+		t.Fatalf("matching ETag should get 304, got %d", w.Code)
+	}
+
+	w = staticRequest(r, "GET", "/assets/js/app.js", "Accept-Encoding", "gzip, br")
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil || w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("ETag") == etag {
+		t.Fatalf("gz variant should be served, got %v", w.Header())
+	}
+	if body, _ := ioutil.ReadAll(zr); string(body) != "console.log('gee')" {
+		t.Fatalf("unexpected gz body %q", body)
+	}
+	if w := staticRequest(r, "GET", "/assets/js/app.js", "Accept-Encoding", "gzip;q=0"); w.Header().Get("Content-Encoding") != "" {
+		t.Fatal("gzip;q=0 shouldn't get the gz variant")
+	}
+
+	if w := staticRequest(r, "HEAD", "/assets/index.html"); w.Code != http.StatusOK || w.Body.Len() != 0 || w.Header().Get("Content-Length") != "12" {
+		t.Fatalf("HEAD should be answered without body, got %d %v", w.Code, w.Header())
+	}
+	if w := staticRequest(r, "GET", "/assets/"); w.Body.String() != "<h1>app</h1>" {
+		t.Fatalf("directories should serve index.html, got %q", w.Body.String())
+	}
+	if w := staticRequest(r, "GET", "/assets/docs"); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/assets/docs/" {
+		t.Fatalf("directories should be redirected to a trailing slash, got %d %v", w.Code, w.Header())
+	}
+	for _, path := range []string{"/assets/docs/", "/assets/missing.js", "/assets/../static_test.go"} {
+		if w := staticRequest(r, "GET", path); w.Code != http.StatusNotFound {
+			t.Fatalf("%s should be 404, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestStaticBrowseAndSPA(t *testing.T) {
+	r := New()
+	r.StaticWithConfig("/files", testFS, StaticConfig{Browse: true})
+	r.StaticWithConfig("/app", testFS, StaticConfig{SPA: true})
+
+	w := staticRequest(r, "GET", "/files/docs/")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `<a href="guide/">guide/</a>`) ||
+		!strings.Contains(w.Body.String(), `<a href="readme.txt">readme.txt</a>`) {
+		t.Fatalf("directory should be listed, got %d %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Cache-Control") != "" {
+		t.Fatal("listings shouldn't be cached")
+	}
+
+	if w := staticRequest(r, "GET", "/app/users/42"); w.Code != http.StatusOK || w.Body.String() != "<h1>app</h1>" {
+		t.Fatalf("unknown paths should fall back to index.html, got %d %q", w.Code, w.Body.String())
+	}
+	if w := staticRequest(r, "GET", "/app/docs/readme.txt"); w.Body.String() != "readme" {
+		t.Fatalf("existing files should be served in SPA mode, got %q", w.Body.String())
+	}
+}
+
+// failingFS fails to list directories and to read files
+type failingFS struct {
+	fstest.MapFS
+}
+
+func (f failingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return nil, errors.New("disk failure")
+}
+
+func (f failingFS) Open(name string) (fs.File, error) {
+	file, err := f.MapFS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return failingFile{file}, nil
+}
+
+type failingFile struct {
+	fs.File
+}
+
+func (failingFile) Read([]byte) (int, error) { return 0, errors.New("disk failure") }
+
+func TestStaticErrors(t *testing.T) {
+	r := New()
+	r.StaticWithConfig("/s", failingFS{testFS}, StaticConfig{Browse: true, Precompressed: true})
+
+	for _, path := range []string{"/s/docs/", "/s/js/app.js"} {
+		w := staticRequest(r, "GET", path, "Accept-Encoding", "gzip")
+		if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Type") != "application/problem+json" ||
+			w.Header().Get("Content-Encoding") != "" || !strings.Contains(w.Body.String(), `"status":500`) {
+			t.Fatalf("%s should render a 500 problem, got %d %v %q", path, w.Code, w.Header(), w.Body.String())
+		}
+	}
+}
+
+func TestStaticDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "main.css"), []byte("body {}"), 0644)
+	os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	modTime := time.Date(2020, 1, 9, 0, 0, 0, 0, time.UTC)
+	os.Chtimes(filepath.Join(dir, "main.css"), modTime, modTime)
+
+	r := New()
+	r.Static("/assets", dir)
+	r.StaticFile("/favicon.css", filepath.Join(dir, "main.css"))
+
+	w := staticRequest(r, "GET", "/assets/main.css")
+	if w.Body.String() != "body {}" || w.Header().Get("Last-Modified") != "Thu, 09 Jan 2020 00:00:00 GMT" ||
+		w.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("unexpected response %q %v", w.Body.String(), w.Header())
+	}
+	if w := staticRequest(r, "GET", "/assets/main.css", "If-Modified-Since", "Thu, 09 Jan 2020 00:00:00 GMT"); w.Code != http.StatusNotModified {
+		t.Fatalf("unmodified file should get 304, got %d", w.Code)
+	}
+	if w := staticRequest(r, "GET", "/assets/sub/"); w.Code != http.StatusNotFound {
+		t.Fatalf("directory listing should be disabled by default, got %d", w.Code)
+	}
+	if w := staticRequest(r, "GET", "/favicon.css"); w.Body.String() != "body {}" {
+		t.Fatalf("StaticFile should serve the file, got %q", w.Body.String())
+	}
+}
@@ -0,0 +1,104 @@
+package gee
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Stream calls step until it returns false or the client goes away,
+// flushing the response after every step. It reports whether the
+// client went away before step was done.
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	done := c.Req.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+			keepOpen := step(c.Writer)
+			c.Writer.Flush()
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
+
+// ServerSentEvent is a single message of a text/event-stream response
+type ServerSentEvent struct {
+	ID    string
+	Event string
+	Retry uint        // reconnection time in milliseconds, 0 to omit
+	Data  interface{} // string and []byte are sent as is, the rest as JSON
+}
+
+// SSEvent writes an event named event with data and flushes it
+func (c *Context) SSEvent(event string, data interface{}) error {
+	return c.WriteSSE(ServerSentEvent{Event: event, Data: data})
+}
+
+// WriteSSE writes e and flushes it, the event-stream headers are set
+// before the first event
+func (c *Context) WriteSSE(e ServerSentEvent) error {
+	if !c.Writer.Written() {
+		header := c.Writer.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		header.Set("X-Accel-Buffering", "no") // disable proxy buffering of nginx
+	}
+
+	var data string
+	switch v := e.Data.(type) {
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	case nil:
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+
+	var str strings.Builder
+	if e.ID != "" {
+		str.WriteString("id: " + sseEscape(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		str.WriteString("event: " + sseEscape(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		str.WriteString(fmt.Sprintf("retry: %d\n", e.Retry))
+	}
+	data = strings.Replace(data, "\r\n", "\n", -1)
+	for _, line := range strings.Split(data, "\n") {
+		str.WriteString("data: " + line + "\n")
+	}
+	str.WriteString("\n")
+
+	if _, err := io.WriteString(c.Writer, str.String()); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+// LastEventID returns the id of the last event the client received before
+// reconnecting, sent by browsers as the Last-Event-ID header
+func (c *Context) LastEventID() string {
+	if id := c.Req.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+	// polyfills that can't set headers send it in the query string
+	return c.Query("lastEventId")
+}
+
+// sseEscape strips line breaks that would end a field early
+func sseEscape(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
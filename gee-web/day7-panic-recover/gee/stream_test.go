@@ -0,0 +1,69 @@
+package gee
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEvent(t *testing.T) {
+	w := httptest.NewRecorder()
+	c := newContext(w, httptest.NewRequest("GET", "/", nil))
+	c.WriteSSE(ServerSentEvent{ID: "1", Event: "message", Data: "hello\nworld"})
+	c.SSEvent("progress", H{"percent": 50})
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type should be text/event-stream, got %s", ct)
+	}
+	want := "id: 1\nevent: message\ndata: hello\ndata: world\n\n" +
+		"event: progress\ndata: {\"percent\":50}\n\n"
+	if w.Body.String() != want {
+		t.Fatalf("unexpected event stream %q", w.Body.String())
+	}
+	if !w.Flushed {
+		t.Fatal("events should be flushed")
+	}
+}
+
+func TestStreamClientGone(t *testing.T) {
+	stopped := make(chan bool, 1)
+	r := New()
+	r.GET("/events", func(c *Context) {
+		id, _ := strconv.Atoi(c.LastEventID())
+		clientGone := c.Stream(func(w io.Writer) bool {
+			id++
+			c.WriteSSE(ServerSentEvent{ID: strconv.Itoa(id), Data: "tick"})
+			time.Sleep(10 * time.Millisecond)
+			return true
+		})
+		stopped <- clientGone
+	})
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+"/events", nil)
+	req.Header.Set("Last-Event-ID", "41")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, _ := bufio.NewReader(res.Body).ReadString('\n')
+	res.Body.Close()
+	if strings.TrimSpace(line) != "id: 42" {
+		t.Fatalf("stream should resume after Last-Event-ID, got %q", line)
+	}
+
+	select {
+	case clientGone := <-stopped:
+		if !clientGone {
+			t.Fatal("Stream should report that the client went away")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stream should stop once the client disconnects")
+	}
+}
@@ -0,0 +1,273 @@
+package gee
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// NewContextForTest returns a Context of engine for w and req that is not
+// pooled, handlers are run as its chain by c.Next(). It is for tests only,
+// through the geetest package, servers get their contexts from ServeHTTP.
+func NewContextForTest(engine *Engine, w http.ResponseWriter, req *http.Request, handlers ...HandlerFunc) *Context {
+	c := &Context{engine: engine}
+	c.reset(w, req)
+	c.handlers = handlers
+	return c
+}
+
+// TestingT is the part of *testing.T used by TestResponse
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// TestClient sends requests to an engine in process, keeping cookies
+// across requests like a browser
+type TestClient struct {
+	engine *Engine
+	jar    http.CookieJar
+}
+
+// Test returns a client sending requests to engine without a network
+func (engine *Engine) Test() *TestClient {
+	jar, _ := cookiejar.New(nil)
+	return &TestClient{engine: engine, jar: jar}
+}
+
+// TestRequest is a request being built by a TestClient
+type TestRequest struct {
+	client *TestClient
+	req    *http.Request
+	body   io.Reader
+	err    error
+}
+
+// Request starts a request of method to target, a path with an optional query
+func (tc *TestClient) Request(method, target string) *TestRequest {
+	return &TestRequest{client: tc, req: httptest.NewRequest(method, target, nil)}
+}
+
+// GET starts a GET request
+func (tc *TestClient) GET(target string) *TestRequest {
+	return tc.Request("GET", target)
+}
+
+// POST starts a POST request
+func (tc *TestClient) POST(target string) *TestRequest {
+	return tc.Request("POST", target)
+}
+
+// PUT starts a PUT request
+func (tc *TestClient) PUT(target string) *TestRequest {
+	return tc.Request("PUT", target)
+}
+
+// PATCH starts a PATCH request
+func (tc *TestClient) PATCH(target string) *TestRequest {
+	return tc.Request("PATCH", target)
+}
+
+// DELETE starts a DELETE request
+func (tc *TestClient) DELETE(target string) *TestRequest {
+	return tc.Request("DELETE", target)
+}
+
+// HEAD starts a HEAD request
+func (tc *TestClient) HEAD(target string) *TestRequest {
+	return tc.Request("HEAD", target)
+}
+
+// Header sets a request header
+func (r *TestRequest) Header(key, value string) *TestRequest {
+	r.req.Header.Set(key, value)
+	return r
+}
+
+// Query adds a query parameter
+func (r *TestRequest) Query(key, value string) *TestRequest {
+	q := r.req.URL.Query()
+	q.Add(key, value)
+	r.req.URL.RawQuery = q.Encode()
+	r.req.RequestURI = r.req.URL.RequestURI()
+	return r
+}
+
+// Cookie adds a cookie besides the ones of the client jar
+func (r *TestRequest) Cookie(cookie *http.Cookie) *TestRequest {
+	r.req.AddCookie(cookie)
+	return r
+}
+
+// Body sets the request body and its Content-Type
+func (r *TestRequest) Body(contentType string, body string) *TestRequest {
+	r.req.Header.Set("Content-Type", contentType)
+	r.body = strings.NewReader(body)
+	return r
+}
+
+// JSONBody sets obj encoded as JSON as the request body
+func (r *TestRequest) JSONBody(obj interface{}) *TestRequest {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	r.req.Header.Set("Content-Type", "application/json")
+	r.body = bytes.NewReader(b)
+	return r
+}
+
+// FormBody sets form as an url-encoded request body
+func (r *TestRequest) FormBody(form url.Values) *TestRequest {
+	return r.Body("application/x-www-form-urlencoded", form.Encode())
+}
+
+// Do sends the request and returns the recorded response
+func (r *TestRequest) Do() *httptest.ResponseRecorder {
+	req := r.req
+	if r.body != nil {
+		req = httptest.NewRequest(req.Method, req.URL.RequestURI(), r.body)
+		req.Header = r.req.Header
+	}
+	// the jar needs an absolute URL, httptest requests are for http://example.com
+	jarURL := &url.URL{Scheme: "http", Host: req.Host, Path: req.URL.Path}
+	if req.TLS != nil {
+		jarURL.Scheme = "https"
+	}
+	for _, cookie := range r.client.jar.Cookies(jarURL) {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.client.engine.ServeHTTP(w, req)
+	r.client.jar.SetCookies(jarURL, w.Result().Cookies())
+	return w
+}
+
+// Expect sends the request and returns its response for assertions reported to t
+func (r *TestRequest) Expect(t TestingT) *TestResponse {
+	t.Helper()
+	if r.err != nil {
+		t.Errorf("gee: build %s %s: %v", r.req.Method, r.req.URL, r.err)
+	}
+	return &TestResponse{t: t, Recorder: r.Do(), name: r.req.Method + " " + r.req.URL.RequestURI()}
+}
+
+// TestResponse checks a response, failed checks are reported with t.Errorf
+type TestResponse struct {
+	Recorder *httptest.ResponseRecorder
+	t        TestingT
+	name     string // method and target of the request
+}
+
+// Status checks the status code
+func (r *TestResponse) Status(code int) *TestResponse {
+	r.t.Helper()
+	if r.Recorder.Code != code {
+		r.t.Errorf("%s: status should be %d, got %d", r.name, code, r.Recorder.Code)
+	}
+	return r
+}
+
+// Header checks the value of a response header
+func (r *TestResponse) Header(key, value string) *TestResponse {
+	r.t.Helper()
+	if got := r.Recorder.Header().Get(key); got != value {
+		r.t.Errorf("%s: header %s should be %q, got %q", r.name, key, value, got)
+	}
+	return r
+}
+
+// Body checks the whole body
+func (r *TestResponse) Body(body string) *TestResponse {
+	r.t.Helper()
+	if got := r.Recorder.Body.String(); got != body {
+		r.t.Errorf("%s: body should be %q, got %q", r.name, body, got)
+	}
+	return r
+}
+
+// BodyContains checks that the body contains s
+func (r *TestResponse) BodyContains(s string) *TestResponse {
+	r.t.Helper()
+	if !strings.Contains(r.Recorder.Body.String(), s) {
+		r.t.Errorf("%s: body should contain %q, got %q", r.name, s, r.Recorder.Body.String())
+	}
+	return r
+}
+
+// Cookie checks the value of a cookie set by the response
+func (r *TestResponse) Cookie(name, value string) *TestResponse {
+	r.t.Helper()
+	for _, cookie := range r.Recorder.Result().Cookies() {
+		if cookie.Name == name {
+			if cookie.Value != value {
+				r.t.Errorf("%s: cookie %s should be %q, got %q", r.name, name, value, cookie.Value)
+			}
+			return r
+		}
+	}
+	r.t.Errorf("%s: cookie %s should be set", r.name, name)
+	return r
+}
+
+// JSONPath checks the value at path in the JSON body. The path is made of
+// object keys and array indexes separated by dots, such as "users.0.name".
+// want is compared after a JSON round trip, so 1 matches the number 1.0.
+func (r *TestResponse) JSONPath(path string, want interface{}) *TestResponse {
+	r.t.Helper()
+	var body interface{}
+	if err := json.Unmarshal(r.Recorder.Body.Bytes(), &body); err != nil {
+		r.t.Errorf("%s: body is not JSON: %v", r.name, err)
+		return r
+	}
+	got, ok := lookupJSONPath(body, path)
+	if !ok {
+		r.t.Errorf("%s: JSON path %s not found in %s", r.name, path, r.Recorder.Body.String())
+		return r
+	}
+	var normalized interface{}
+	b, err := json.Marshal(want)
+	if err == nil {
+		err = json.Unmarshal(b, &normalized)
+	}
+	if err != nil {
+		r.t.Errorf("%s: can't encode %v as JSON: %v", r.name, want, err)
+		return r
+	}
+	if !reflect.DeepEqual(got, normalized) {
+		r.t.Errorf("%s: JSON path %s should be %v, got %v", r.name, path, normalized, got)
+	}
+	return r
+}
+
+func lookupJSONPath(v interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return v, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = node[key]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
@@ -0,0 +1,65 @@
+package gee
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// recordT records the failures of assertions
+type recordT struct {
+	errors []string
+}
+
+func (t *recordT) Helper() {}
+
+func (t *recordT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestTestClient(t *testing.T) {
+	r := New()
+	r.POST("/users", func(c *Context) {
+		var obj struct {
+			Name string `json:"name"`
+		}
+		if err := c.ShouldBindJSON(&obj); err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.SetHeader("X-Trace", c.Req.Header.Get("X-Trace"))
+		c.JSON(http.StatusCreated, H{"user": H{"name": obj.Name, "tags": []string{"a", c.Query("tag")}, "id": 1}})
+	})
+	r.POST("/login", func(c *Context) {
+		c.SetCookie("session", c.PostForm("user"), 3600, "/", "", false, true)
+	})
+	r.GET("/me", func(c *Context) {
+		user, _ := c.Cookie("session")
+		c.String(http.StatusOK, "hello %s", user)
+	})
+
+	client := r.Test()
+	client.POST("/users").Query("tag", "b").Header("X-Trace", "t1").JSONBody(H{"name": "gee"}).
+		Expect(t).
+		Status(http.StatusCreated).
+		Header("X-Trace", "t1").
+		JSONPath("user.name", "gee").
+		JSONPath("user.id", 1).
+		JSONPath("user.tags.1", "b").
+		JSONPath("user", map[string]interface{}{"name": "gee", "id": 1, "tags": []string{"a", "b"}})
+
+	client.POST("/login").FormBody(url.Values{"user": {"geektutu"}}).Expect(t).Cookie("session", "geektutu")
+	client.GET("/me").Expect(t).Body("hello geektutu").BodyContains("geektutu")
+	r.Test().GET("/me").Expect(t).Body("hello ")
+
+	rt := &recordT{}
+	client.POST("/users").JSONBody(H{"name": "gee"}).Expect(rt).
+		Status(http.StatusOK).
+		JSONPath("user.name", "other").
+		JSONPath("user.missing", 1).
+		Cookie("session", "x")
+	if len(rt.errors) != 4 {
+		t.Fatalf("failed checks should be reported, got %q", rt.errors)
+	}
+}
@@ -0,0 +1,213 @@
+package gee
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TimeoutConfig configures the Timeout middleware
+type TimeoutConfig struct {
+	Timeout time.Duration
+	// Status is the status of timed out responses, 503 by default,
+	// 504 suits handlers waiting for upstream services
+	Status int
+}
+
+// Timeout replies 503 when the handlers after it take longer than d
+func Timeout(d time.Duration) HandlerFunc {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: d})
+}
+
+// TimeoutWithConfig runs the rest of the chain with a deadline on the request
+// context, which handlers watch through c.Done() or by passing c to context
+// aware calls. The response is buffered until the handlers return, so a timed
+// out response is never mixed with a late one: once the deadline passes, the
+// timeout problem is sent and later writes fail with http.ErrHandlerTimeout.
+// Handlers after it must not stream or hijack the connection.
+func TimeoutWithConfig(config TimeoutConfig) HandlerFunc {
+	status := config.Status
+	if status == 0 {
+		status = http.StatusServiceUnavailable
+	}
+
+	return func(c *Context) {
+		ctx, cancel := context.WithTimeout(c.Req.Context(), config.Timeout)
+		defer cancel()
+
+		origin := c.Writer.Header().Clone()
+		tw := &timeoutWriter{ResponseWriter: c.Writer, header: origin.Clone(), status: http.StatusOK}
+		fc := c.fork(tw, c.Req.WithContext(ctx))
+		done := make(chan struct{})
+		panicked := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicked <- p
+				}
+			}()
+			fc.Next()
+			close(done)
+		}()
+
+		select {
+		case p := <-panicked:
+			panic(p)
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			c.Abort()
+			c.Errors = fc.Errors
+			c.Keys = fc.Keys
+			c.StatusCode = fc.StatusCode
+			mergeHeader(c.Writer.Header(), origin, tw.header)
+			c.Writer.WriteHeader(tw.status)
+			if tw.wroteHeader {
+				c.Writer.Write(tw.buf.Bytes())
+			}
+		case <-ctx.Done():
+			tw.mu.Lock()
+			tw.timedOut = true
+			tw.mu.Unlock()
+			go discardPanic(panicked, done)
+			c.AbortWithProblem(status, http.ErrHandlerTimeout)
+		}
+	}
+}
+
+// mergeHeader applies to dst the changes the handlers made from origin to
+// header, keeping the keys set on dst meanwhile
+func mergeHeader(dst, origin, header http.Header) {
+	for k := range origin {
+		if _, ok := header[k]; !ok {
+			delete(dst, k)
+		}
+	}
+	for k, v := range header {
+		if !equalValues(origin[k], v) {
+			dst[k] = v
+		}
+	}
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// discardPanic logs a panic of a timed out handler, nobody can recover it
+func discardPanic(panicked chan interface{}, done chan struct{}) {
+	select {
+	case p := <-panicked:
+		log.Printf("gee: timed out handler panicked: %v", p)
+	case <-done:
+	}
+}
+
+// fork returns a copy of c running on w and req, which the handlers after the
+// current one use from another goroutine while c may be recycled
+func (c *Context) fork(w ResponseWriter, req *http.Request) *Context {
+	fc := &Context{
+		Writer:      w,
+		Req:         req,
+		Path:        c.Path,
+		Method:      c.Method,
+		Params:      append(Params(nil), c.Params...),
+		fullPath:    c.fullPath,
+		templateSet: c.templateSet,
+		Errors:      append([]*Error(nil), c.Errors...),
+		handlers:    c.handlers,
+		index:       c.index,
+		engine:      c.engine,
+	}
+	if c.Keys != nil {
+		fc.Keys = make(map[string]interface{}, len(c.Keys))
+		for k, v := range c.Keys {
+			fc.Keys[k] = v
+		}
+	}
+	return fc
+}
+
+// timeoutWriter buffers the response of handlers running under Timeout
+type timeoutWriter struct {
+	ResponseWriter // the writer of the request, used for Unwrap only
+	mu             sync.Mutex
+	header         http.Header
+	buf            bytes.Buffer
+	status         int
+	wroteHeader    bool
+	timedOut       bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.wroteHeader = true
+	return w.buf.Write(data)
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if code > 0 && !w.wroteHeader && !w.timedOut {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.wroteHeader = true
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.wroteHeader {
+		return noWritten
+	}
+	return w.buf.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.wroteHeader
+}
+
+// Flush does nothing, the response is sent when the handlers return
+func (w *timeoutWriter) Flush() {}
+
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, http.ErrNotSupported
+}
+
+func (w *timeoutWriter) Push(target string, opts *http.PushOptions) error {
+	return http.ErrNotSupported
+}
@@ -0,0 +1,127 @@
+package gee
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	late := make(chan error, 1)
+	timedOut := make(chan struct{})
+	r := New()
+	r.Use(Timeout(20 * time.Millisecond))
+	r.GET("/fast", func(c *Context) {
+		c.SetHeader("X-Fast", "1")
+		c.String(http.StatusCreated, "fast")
+	})
+	r.GET("/slow", func(c *Context) {
+		<-c.Done()
+		<-timedOut
+		_, err := c.Writer.Write([]byte("late"))
+		late <- err
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/fast", nil))
+	if w.Code != http.StatusCreated || w.Body.String() != "fast" || w.Header().Get("X-Fast") != "1" {
+		t.Fatalf("fast handler should answer, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("slow handler should time out with 503, got %d", w.Code)
+	}
+	close(timedOut)
+	if err := <-late; err != http.ErrHandlerTimeout {
+		t.Fatalf("late writes should fail, got %v", err)
+	}
+}
+
+func TestTimeoutStatusAndPanic(t *testing.T) {
+	r := New()
+	r.Use(Recovery(), TimeoutWithConfig(TimeoutConfig{Timeout: 10 * time.Millisecond, Status: http.StatusGatewayTimeout}))
+	r.GET("/slow", func(c *Context) { <-c.Done() })
+	r.GET("/panic", func(c *Context) { panic("boom") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("panics should reach Recovery, got %d", w.Code)
+	}
+}
+
+func TestTimeoutSessions(t *testing.T) {
+	r := New()
+	r.Use(func(c *Context) {
+		c.SetHeader("X-Kept", "1")
+		c.SetHeader("X-Dropped", "1")
+		c.Next()
+	}, Sessions("s", NewCookieStore([]byte("hash-key"))), Timeout(time.Second))
+	r.GET("/", func(c *Context) {
+		c.Writer.Header().Del("X-Dropped")
+		c.Session().Set("user", "gee")
+		if err := c.Session().Save(); err != nil {
+			t.Error(err)
+		}
+		if c.Writer.Header().Get("Set-Cookie") == "" {
+			t.Error("Save should write to the buffered response of the handler")
+		}
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || cookies[0].Name != "s" {
+		t.Fatalf("session saved under Timeout should set its cookie, got %v", cookies)
+	}
+	if w.Header().Get("X-Kept") != "1" || w.Header().Get("X-Dropped") != "" {
+		t.Fatalf("headers should be merged with the changes of the handler, got %v", w.Header())
+	}
+}
+
+func TestContextAsContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), "user", "req"))
+	req := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	c := newContext(httptest.NewRecorder(), req)
+	c.Set("key", "keys")
+
+	if c.Value("key") != "keys" || c.Value("user") != "req" {
+		t.Fatalf("Value should look up Keys then the request, got %v %v", c.Value("key"), c.Value("user"))
+	}
+	if _, ok := c.Deadline(); ok || c.Err() != nil {
+		t.Fatal("context shouldn't be done yet")
+	}
+	cancel()
+	<-c.Done()
+	if c.Err() != context.Canceled {
+		t.Fatalf("Err should be Canceled, got %v", c.Err())
+	}
+}
+
+func TestContextCopy(t *testing.T) {
+	r := New()
+	copies := make(chan *Context, 2)
+	r.GET("/users/:id", func(c *Context) {
+		c.Set("user", c.Param("id"))
+		c.String(http.StatusAccepted, "ok")
+		copies <- c.Copy()
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/1", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/2", nil))
+
+	cp := <-copies
+	if cp.Req.URL.Path != "/users/1" || cp.Param("id") != "1" || cp.Value("user") != "1" ||
+		cp.FullPath() != "/users/:id" || cp.Writer.Status() != http.StatusAccepted {
+		t.Fatalf("copy should keep the first request, got %s %s %v", cp.Req.URL.Path, cp.Param("id"), cp.Value("user"))
+	}
+	cp.Next()
+}
@@ -5,48 +5,213 @@ import (
 	"strings"
 )
 
+type nodeKind uint8
+
+const (
+	static nodeKind = iota
+	param
+	catchAll
+)
+
+// node is a compressed radix tree node. Static nodes hold a shared
+// path prefix, wildcard nodes hold ":name", "{name:expr}" or "*name"
+// for a whole segment.
 type node struct {
-	pattern  string
-	part     string
-	children []*node
-	isWild   bool
+	path       string
+	kind       nodeKind
+	key        string        // param name of wildcard nodes
+	constraint *constraint   // values matched by a {name:expr} param, nil for any
+	pattern    string        // full route pattern, empty if no route ends here
+	name       string        // optional route name, see Route.Name
+	handlers   []HandlerFunc // complete handler chain of the route ending here
+	indices    string        // first byte of each static child, for fast lookup
+	children   []*node       // static children
+	wilds      []*node       // param children, constrained ones first
+	catchAll   *node         // *catchall child
 }
 
 func (n *node) String() string {
-	return fmt.Sprintf("node{pattern=%s, part=%s, isWild=%t}", n.pattern, n.part, n.isWild)
+	return fmt.Sprintf("node{pattern=%s, path=%s, isWild=%t}", n.pattern, n.path, n.kind != static)
 }
 
-func (n *node) insert(pattern string, parts []string, height int) {
-	if len(parts) == height {
-		n.pattern = pattern
-		return
+// longestPrefix returns the length of the common prefix of a and b
+func longestPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
 	}
+	return i
+}
 
-	part := parts[height]
-	child := n.matchChild(part)
-	if child == nil {
-		child = &node{part: part, isWild: part[0] == ':' || part[0] == '*'}
-		n.children = append(n.children, child)
+// insert adds pattern to the tree rooted at n and returns its node, n must be the root.
+// It panics when pattern is ambiguous with a route already in the tree:
+// a different wildcard name with the same constraint at the same position,
+// or the same pattern twice.
+func (n *node) insert(pattern string, handlers []HandlerFunc) *node {
+	path := pattern
+	for {
+		// find the next wildcard, which always starts a segment
+		start := -1
+		for i := 0; i < len(path); i++ {
+			if (path[i] == ':' || path[i] == '*' || path[i] == '{') && (i == 0 || path[i-1] == '/') {
+				start = i
+				break
+			}
+		}
+		if start < 0 {
+			n = n.insertStatic(path)
+			break
+		}
+		if start > 0 {
+			n = n.insertStatic(path[:start])
+		}
+
+		end := strings.IndexByte(path[start:], '/')
+		if end < 0 {
+			end = len(path)
+		} else {
+			end += start
+		}
+		n = n.insertWild(pattern, path[start:end])
+		path = path[end:]
+		if path == "" {
+			break
+		}
+	}
+
+	if n.pattern != "" {
+		panic(fmt.Sprintf("gee: route %s conflicts with existing route %s", pattern, n.pattern))
 	}
-	child.insert(pattern, parts, height+1)
+	n.pattern = pattern
+	n.handlers = handlers
+	return n
 }
 
-func (n *node) search(parts []string, height int) *node {
-	if len(parts) == height || strings.HasPrefix(n.part, "*") {
+// insertStatic walks or splits static nodes below n until path is consumed
+func (n *node) insertStatic(path string) *node {
+	for path != "" {
+		c := path[0]
+		i := strings.IndexByte(n.indices, c)
+		if i < 0 {
+			child := &node{path: path, kind: static}
+			n.indices += string(c)
+			n.children = append(n.children, child)
+			return child
+		}
+
+		child := n.children[i]
+		l := longestPrefix(path, child.path)
+		if l < len(child.path) {
+			// split child under a new prefix node, child keeps its route and
+			// everything that hung below it, so that *node pointers stay valid
+			prefix := &node{
+				path:     child.path[:l],
+				kind:     static,
+				indices:  child.path[l : l+1],
+				children: []*node{child},
+			}
+			child.path = child.path[l:]
+			n.children[i] = prefix
+			child = prefix
+		}
+		n = child
+		path = path[l:]
+	}
+	return n
+}
+
+// insertWild returns the wildcard child of n named part, creating it if needed.
+// Params with different constraints share a position, they are tried in
+// registration order and the unconstrained one last.
+func (n *node) insertWild(pattern string, part string) *node {
+	key, expr := splitWildcard(part)
+	if part[0] == '*' {
+		if n.catchAll == nil {
+			n.catchAll = &node{path: part, kind: catchAll, key: key}
+		} else if n.catchAll.path != part {
+			panic(fmt.Sprintf("gee: wildcard %s in route %s conflicts with existing wildcard %s", part, pattern, n.catchAll.path))
+		}
+		return n.catchAll
+	}
+
+	for _, child := range n.wilds {
+		childExpr := ""
+		if child.constraint != nil {
+			childExpr = child.constraint.expr
+		}
+		if childExpr != expr {
+			continue
+		}
+		if child.key != key {
+			panic(fmt.Sprintf("gee: wildcard %s in route %s conflicts with existing wildcard %s", part, pattern, child.path))
+		}
+		return child
+	}
+
+	child := &node{path: part, kind: param, key: key}
+	if expr == "" {
+		n.wilds = append(n.wilds, child)
+		return child
+	}
+	child.constraint = newConstraint(pattern, expr)
+	i := len(n.wilds)
+	if i > 0 && n.wilds[i-1].constraint == nil {
+		i--
+	}
+	n.wilds = append(n.wilds, nil)
+	copy(n.wilds[i+1:], n.wilds[i:])
+	n.wilds[i] = child
+	return child
+}
+
+// search matches the remaining path below n with priority
+// static > :param > *catchall, backtracking to the next candidate when a
+// branch has no route or a param constraint rejects the segment. Matched
+// params are appended to ps, so lookups don't allocate as long as ps has
+// enough capacity.
+func (n *node) search(path string, ps *Params) *node {
+	if path == "" {
 		if n.pattern == "" {
 			return nil
 		}
 		return n
 	}
 
-	part := parts[height]
-	children := n.matchChildren(part)
+	if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+		child := n.children[i]
+		if strings.HasPrefix(path, child.path) {
+			if result := child.search(path[len(child.path):], ps); result != nil {
+				return result
+			}
+		}
+	}
+
+	if len(n.wilds) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			value := path[:end]
+			for _, child := range n.wilds {
+				if child.constraint != nil && !child.constraint.match(value) {
+					continue
+				}
+				l := len(*ps)
+				*ps = append(*ps, Param{Key: child.key, Value: value})
+				if result := child.search(path[end:], ps); result != nil {
+					return result
+				}
+				*ps = (*ps)[:l]
+			}
+		}
+	}
 
-	for _, child := range children {
-		result := child.search(parts, height+1)
-		if result != nil {
-			return result
+	if child := n.catchAll; child != nil && child.pattern != "" {
+		if child.key != "" {
+			*ps = append(*ps, Param{Key: child.key, Value: path})
 		}
+		return child
 	}
 
 	return nil
@@ -59,23 +224,10 @@ func (n *node) travel(list *([]*node)) {
 	for _, child := range n.children {
 		child.travel(list)
 	}
-}
-
-func (n *node) matchChild(part string) *node {
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			return child
-		}
+	for _, child := range n.wilds {
+		child.travel(list)
 	}
-	return nil
-}
-
-func (n *node) matchChildren(part string) []*node {
-	nodes := make([]*node, 0)
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			nodes = append(nodes, child)
-		}
+	if n.catchAll != nil {
+		n.catchAll.travel(list)
 	}
-	return nodes
 }
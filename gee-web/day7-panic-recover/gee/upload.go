@@ -0,0 +1,146 @@
+package gee
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// ErrBodyTooLarge is returned when the request body exceeds the engine limit,
+// the 413 problem is then already sent
+var ErrBodyTooLarge = errors.New("gee: request body too large")
+
+// SetMaxBodyBytes limits the size of request bodies, 0 means no limit.
+// Reading past it fails, and the upload helpers and Bind reply 413.
+func (engine *Engine) SetMaxBodyBytes(n int64) {
+	engine.maxBodyBytes = n
+}
+
+// SetMaxMultipartMemory sets the memory used to parse multipart forms,
+// larger files are stored in temporary files. Default is 32 MiB.
+func (engine *Engine) SetMaxMultipartMemory(n int64) {
+	engine.maxMultipartMemory = n
+}
+
+// MaxBodyBytes returns the request body limit of the engine, 0 for none
+func (c *Context) MaxBodyBytes() int64 {
+	if c.engine == nil {
+		return 0
+	}
+	return c.engine.maxBodyBytes
+}
+
+// limitBody wraps the body of c.Req with the engine limit
+func (c *Context) limitBody() {
+	if c.engine != nil && c.engine.maxBodyBytes > 0 && c.Req.Body != nil && c.Req.Body != http.NoBody {
+		c.Req.Body = http.MaxBytesReader(c.Writer, c.Req.Body, c.engine.maxBodyBytes)
+	}
+}
+
+func (c *Context) multipartMemory() int64 {
+	if c.engine != nil && c.engine.maxMultipartMemory > 0 {
+		return c.engine.maxMultipartMemory
+	}
+	return defaultMultipartMemory
+}
+
+// bodyError replies 413 and returns ErrBodyTooLarge if err comes from the body limit
+func (c *Context) bodyError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		if !c.IsAborted() {
+			c.AbortWithProblem(http.StatusRequestEntityTooLarge, ErrBodyTooLarge)
+		}
+		return ErrBodyTooLarge
+	}
+	return err
+}
+
+// checkBodySize replies 413 when the declared length is over the limit
+func (c *Context) checkBodySize() error {
+	if c.engine != nil && c.engine.maxBodyBytes > 0 && c.Req.ContentLength > c.engine.maxBodyBytes {
+		c.AbortWithProblem(http.StatusRequestEntityTooLarge, ErrBodyTooLarge)
+		return ErrBodyTooLarge
+	}
+	return nil
+}
+
+// MultipartForm parses the multipart form, keeping up to the engine max
+// multipart memory in memory and the rest in temporary files
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if c.Req.MultipartForm != nil {
+		return c.Req.MultipartForm, nil
+	}
+	if err := c.checkBodySize(); err != nil {
+		return nil, err
+	}
+	if err := c.Req.ParseMultipartForm(c.multipartMemory()); err != nil {
+		return nil, c.bodyError(err)
+	}
+	return c.Req.MultipartForm, nil
+}
+
+// FormFile returns the first file of the multipart form field name
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	files := form.File[name]
+	if len(files) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	return files[0], nil
+}
+
+// SaveUploadedFile copies the uploaded file to dst, creating its directory
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// StreamMultipart calls fn for every part of a multipart body as it arrives,
+// without buffering files in memory or on disk. Reading a part is only
+// possible until fn returns, an error of fn stops the stream and is returned.
+func (c *Context) StreamMultipart(fn func(part *multipart.Part) error) error {
+	if err := c.checkBodySize(); err != nil {
+		return err
+	}
+	reader, err := c.Req.MultipartReader()
+	if err != nil {
+		return err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return c.bodyError(err)
+		}
+		err = fn(part)
+		part.Close()
+		if err != nil {
+			return c.bodyError(err)
+		}
+	}
+}
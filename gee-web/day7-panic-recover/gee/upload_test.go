@@ -0,0 +1,151 @@
+package gee
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// multipartBody returns a multipart body with field values and files of the given contents
+func multipartBody(fields map[string]string, files map[string]string) (*bytes.Buffer, string) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	for name, content := range files {
+		fw, _ := mw.CreateFormFile(name, name+".txt")
+		io.WriteString(fw, content)
+	}
+	mw.Close()
+	return &buf, mw.FormDataContentType()
+}
+
+func uploadRequest(r *Engine, path string, body io.Reader, contentType string, length int64) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, body)
+	req.Header.Set("Content-Type", contentType)
+	req.ContentLength = length
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestFormFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := New()
+	r.SetMaxMultipartMemory(1 << 10)
+	r.POST("/upload", func(c *Context) {
+		form, err := c.MultipartForm()
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		fh, err := c.FormFile("avatar")
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := c.SaveUploadedFile(fh, filepath.Join(dir, "users", fh.Filename)); err != nil {
+			c.Fail(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "%s %s %d", form.Value["user"][0], fh.Filename, fh.Size)
+	})
+
+	content := strings.Repeat("x", 4<<10)
+	body, contentType := multipartBody(map[string]string{"user": "gee"}, map[string]string{"avatar": content})
+	w := uploadRequest(r, "/upload", body, contentType, int64(body.Len()))
+	if w.Body.String() != "gee avatar.txt 4096" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+	if saved, _ := ioutil.ReadFile(filepath.Join(dir, "users", "avatar.txt")); string(saved) != content {
+		t.Fatal("uploaded file should be saved")
+	}
+
+	body, contentType = multipartBody(map[string]string{"user": "gee"}, nil)
+	if w := uploadRequest(r, "/upload", body, contentType, int64(body.Len())); w.Code != http.StatusBadRequest {
+		t.Fatalf("missing file should fail, got %d", w.Code)
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	r := New()
+	r.SetMaxBodyBytes(1 << 10)
+	r.POST("/upload", func(c *Context) {
+		if _, err := c.FormFile("file"); err != nil {
+			return
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	r.POST("/bind", func(c *Context) {
+		var obj struct{ Name string }
+		if c.Bind(&obj) == nil {
+			c.String(http.StatusOK, "ok")
+		}
+	})
+
+	body, contentType := multipartBody(nil, map[string]string{"file": strings.Repeat("x", 2<<10)})
+	if w := uploadRequest(r, "/upload", bytes.NewReader(body.Bytes()), contentType, int64(body.Len())); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("declared length over the limit should get 413, got %d", w.Code)
+	}
+	// chunked bodies are only caught while reading
+	if w := uploadRequest(r, "/upload", body, contentType, -1); w.Code != http.StatusRequestEntityTooLarge ||
+		w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("body over the limit should get 413, got %d", w.Code)
+	}
+
+	json := `{"Name":"` + strings.Repeat("x", 2<<10) + `"}`
+	if w := uploadRequest(r, "/bind", strings.NewReader(json), "application/json", -1); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Bind should reply 413, got %d", w.Code)
+	}
+	if w := uploadRequest(r, "/bind", strings.NewReader(`{"Name":"gee"}`), "application/json", -1); w.Code != http.StatusOK {
+		t.Fatalf("small bodies should pass, got %d", w.Code)
+	}
+}
+
+func TestStreamMultipart(t *testing.T) {
+	r := New()
+	r.SetMaxBodyBytes(64 << 10)
+	r.POST("/stream", func(c *Context) {
+		var names []string
+		var size int64
+		err := c.StreamMultipart(func(part *multipart.Part) error {
+			n, err := io.Copy(ioutil.Discard, part)
+			names = append(names, part.FormName())
+			size += n
+			return err
+		})
+		if err != nil {
+			if err != ErrBodyTooLarge {
+				c.Fail(http.StatusBadRequest, err.Error())
+			}
+			return
+		}
+		c.String(http.StatusOK, "%s %d", strings.Join(names, ","), size)
+	})
+
+	body, contentType := multipartBody(map[string]string{"user": "gee"}, map[string]string{"big": strings.Repeat("x", 32<<10)})
+	if w := uploadRequest(r, "/stream", body, contentType, -1); w.Body.String() != "user,big 32771" {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+
+	body, contentType = multipartBody(nil, map[string]string{"big": strings.Repeat("x", 128<<10)})
+	if w := uploadRequest(r, "/stream", body, contentType, -1); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("stream over the limit should get 413, got %d", w.Code)
+	}
+	if w := uploadRequest(r, "/stream", strings.NewReader("x"), "text/plain", 1); w.Code != http.StatusBadRequest {
+		t.Fatalf("non multipart body should fail, got %d", w.Code)
+	}
+}
@@ -0,0 +1,78 @@
+package gee
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Route is a registered route, returned so that it can be named
+type Route struct {
+	Method  string
+	Pattern string
+	engine  *Engine
+	nodes   []*node // one per method, several for Any
+}
+
+// Name names the route so engine.URL can build paths for it,
+// it panics if the name is already used by another pattern
+func (r *Route) Name(name string) *Route {
+	engine := r.engine
+	if pattern, ok := engine.namedRoutes[name]; ok && pattern != r.Pattern {
+		panic(fmt.Sprintf("gee: route name %s is already used by %s", name, pattern))
+	}
+	if engine.namedRoutes == nil {
+		engine.namedRoutes = make(map[string]string)
+	}
+	engine.namedRoutes[name] = r.Pattern
+	for _, n := range r.nodes {
+		n.name = name
+	}
+	return r
+}
+
+// URL builds the path of the route named name, params are key/value pairs
+// filling its :param and *wildcard parts, e.g. URL("user", "name", "geektutu").
+// Values are escaped, a *wildcard keeps its slashes.
+func (engine *Engine) URL(name string, params ...string) (string, error) {
+	pattern, ok := engine.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("gee: no route named %s", name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("gee: odd number of params for route %s", name)
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	parts := strings.Split(pattern, "/")
+	for i, part := range parts {
+		if part == "" || (part[0] != ':' && part[0] != '*' && part[0] != '{') {
+			continue
+		}
+		key, _ := splitWildcard(part)
+		value, ok := values[key]
+		if !ok && key != "" {
+			return "", fmt.Errorf("gee: missing param %s for route %s", key, name)
+		}
+		delete(values, key)
+		if part[0] == '*' {
+			segments := strings.Split(strings.TrimPrefix(value, "/"), "/")
+			for j, segment := range segments {
+				segments[j] = url.PathEscape(segment)
+			}
+			parts[i] = strings.Join(segments, "/")
+		} else {
+			if value == "" {
+				return "", fmt.Errorf("gee: empty param %s for route %s", key, name)
+			}
+			parts[i] = url.PathEscape(value)
+		}
+	}
+	for key := range values {
+		return "", fmt.Errorf("gee: unknown param %s for route %s", key, name)
+	}
+	return strings.Join(parts, "/"), nil
+}
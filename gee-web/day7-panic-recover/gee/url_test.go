@@ -0,0 +1,74 @@
+package gee
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+)
+
+func TestURL(t *testing.T) {
+	r := New()
+	v1 := r.Group("/v1")
+	v1.GET("/hello/:name", func(c *Context) {}).Name("hello")
+	v1.Any("/users/:id/files/*filepath", func(c *Context) {}).Name("files")
+	v1.GET("/posts/{id:int}", func(c *Context) {}).Name("post")
+
+	cases := []struct {
+		name   string
+		params []string
+		url    string
+	}{
+		{"hello", []string{"name", "geektutu"}, "/v1/hello/geektutu"},
+		{"hello", []string{"name", "a b/c"}, "/v1/hello/a%20b%2Fc"},
+		{"files", []string{"id", "1", "filepath", "css/main style.css"}, "/v1/users/1/files/css/main%20style.css"},
+		{"post", []string{"id", "42"}, "/v1/posts/42"},
+	}
+	for _, c := range cases {
+		if u, err := r.URL(c.name, c.params...); err != nil || u != c.url {
+			t.Fatalf("URL(%s, %v) should be %s, got %s %v", c.name, c.params, c.url, u, err)
+		}
+	}
+
+	for _, params := range [][]string{{}, {"name"}, {"name", "a", "age", "1"}} {
+		if _, err := r.URL("hello", params...); err == nil {
+			t.Fatalf("URL(hello, %v) should fail", params)
+		}
+	}
+	if _, err := r.URL("nothing"); err == nil {
+		t.Fatal("unknown route name should fail")
+	}
+
+	n, _ := r.router.getRoute("PUT", "/v1/users/1/files/a")
+	if n == nil || n.name != "files" {
+		t.Fatal("Any should name the route of every method")
+	}
+}
+
+func TestNameAfterSplit(t *testing.T) {
+	r := New()
+	users := r.GET("/users", func(c *Context) {})
+	r.GET("/u", func(c *Context) {})
+	users.Name("users")
+
+	if u, err := r.URL("users"); err != nil || u != "/users" {
+		t.Fatalf("URL(users) should be /users, got %s %v", u, err)
+	}
+	for _, route := range r.Routes() {
+		if (route.Pattern == "/users") != (route.Name == "users") {
+			t.Fatalf("only /users should be named, got %+v", route)
+		}
+	}
+}
+
+func TestURLTemplateFunc(t *testing.T) {
+	r := New()
+	r.GET("/hello/:name", func(c *Context) {}).Name("hello")
+	tmpl := template.Must(template.New("").Funcs(r.templateFuncs()).Parse(`<a href="{{url "hello" "name" .}}">`))
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, "geektutu"); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != `<a href="/hello/geektutu">` {
+		t.Fatalf("unexpected template output %s", buf.String())
+	}
+}
@@ -0,0 +1,164 @@
+package gee
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError describes a field that failed a rule of its `binding` tag
+type FieldError struct {
+	Field string      // path of the field, e.g. Address.City
+	Tag   string      // failed rule, e.g. min
+	Param string      // rule parameter, e.g. 3 for min=3
+	Value interface{} // actual value of the field
+}
+
+func (e FieldError) Error() string {
+	if e.Param != "" {
+		return fmt.Sprintf("field '%s' failed on the '%s=%s' rule", e.Field, e.Tag, e.Param)
+	}
+	return fmt.Sprintf("field '%s' failed on the '%s' rule", e.Field, e.Tag)
+}
+
+// ValidationErrors is the list of every field error found by Validate
+type ValidationErrors []FieldError
+
+func (es ValidationErrors) Error() string {
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+var emailRegexp = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+
+// Validate checks the rules in the `binding` tags of the struct obj points to.
+// Supported rules are required, omitempty, min=N, max=N, oneof=a b c and email,
+// min and max compare numbers by value and strings, slices and maps by length.
+// It returns ValidationErrors, or nil when every rule passes.
+func Validate(obj interface{}) error {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs ValidationErrors
+	validateStruct(v, "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateStruct(v reflect.Value, prefix string, errs *ValidationErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := prefix + field.Name
+		fv := v.Field(i)
+		if rules := field.Tag.Get("binding"); rules != "" && rules != "-" {
+			validateField(fv, name, rules, errs)
+		}
+
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+			validateStruct(fv, name+".", errs)
+		}
+	}
+}
+
+func validateField(fv reflect.Value, name string, rules string, errs *ValidationErrors) {
+	for _, rule := range strings.Split(rules, ",") {
+		tag, param := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			tag, param = rule[:i], rule[i+1:]
+		}
+		zero := fv.IsZero()
+		if tag == "omitempty" {
+			if zero {
+				return
+			}
+			continue
+		}
+		if tag == "required" {
+			if zero {
+				*errs = append(*errs, FieldError{Field: name, Tag: tag})
+				return
+			}
+			continue
+		}
+
+		value := fv
+		for value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return
+			}
+			value = value.Elem()
+		}
+		ok, err := checkRule(value, tag, param)
+		if err != nil {
+			panic(fmt.Sprintf("gee: invalid binding rule %q on field %s: %v", rule, name, err))
+		}
+		if !ok {
+			*errs = append(*errs, FieldError{Field: name, Tag: tag, Param: param, Value: value.Interface()})
+		}
+	}
+}
+
+func checkRule(v reflect.Value, tag string, param string) (bool, error) {
+	switch tag {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return false, err
+		}
+		var n float64
+		switch v.Kind() {
+		case reflect.String:
+			n = float64(utf8.RuneCountInString(v.String()))
+		case reflect.Slice, reflect.Map, reflect.Array:
+			n = float64(v.Len())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = float64(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			n = v.Float()
+		default:
+			return false, fmt.Errorf("unsupported type %s", v.Type())
+		}
+		if tag == "min" {
+			return n >= limit, nil
+		}
+		return n <= limit, nil
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		for _, option := range strings.Fields(param) {
+			if s == option {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "email":
+		if v.Kind() != reflect.String {
+			return false, fmt.Errorf("unsupported type %s", v.Type())
+		}
+		return emailRegexp.MatchString(v.String()), nil
+	}
+	return false, fmt.Errorf("unknown rule %s", tag)
+}
@@ -0,0 +1,535 @@
+package gee
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Message types, the values are the RFC 6455 opcodes
+const (
+	continuationFrame = 0
+	TextMessage       = 1
+	BinaryMessage     = 2
+	CloseMessage      = 8
+	PingMessage       = 9
+	PongMessage       = 10
+)
+
+// Close codes defined in RFC 6455, section 7.4.1
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseInternalServerErr       = 1011
+)
+
+const (
+	websocketGUID          = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	defaultMaxMessageSize  = 1 << 20
+	maxControlPayload      = 125
+	closeHandshakeDeadline = time.Second
+)
+
+var (
+	// ErrReadLimit is returned when a message is larger than the read limit
+	ErrReadLimit = errors.New("gee: websocket message exceeds read limit")
+	// ErrCloseSent is returned when writing after a close frame was sent
+	ErrCloseSent = errors.New("gee: websocket close sent")
+)
+
+// CloseError is returned by ReadMessage when the peer sent a close frame
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("gee: websocket closed with code %d %s", e.Code, e.Text)
+}
+
+// Conn is a WebSocket connection. ReadMessage must be called from one
+// goroutine and NextWriter/WriteMessage from one goroutine, control frames
+// like Ping and Close may be written concurrently with both.
+type Conn struct {
+	conn     net.Conn
+	br       *bufio.Reader
+	isServer bool
+
+	writeMu   sync.Mutex
+	closeSent bool
+
+	readLimit int64
+	readErr   error
+
+	pingHandler func(data []byte) error
+	pongHandler func(data []byte) error
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, isServer bool) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	c := &Conn{conn: conn, br: br, isServer: isServer, readLimit: defaultMaxMessageSize}
+	c.pingHandler = func(data []byte) error {
+		err := c.WriteControl(PongMessage, data)
+		if err == ErrCloseSent {
+			return nil
+		}
+		return err
+	}
+	c.pongHandler = func([]byte) error { return nil }
+	return c
+}
+
+// headerContainsToken reports whether the comma separated header name contains token
+func headerContainsToken(header http.Header, name string, token string) bool {
+	for _, v := range header[name] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func computeAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// WebSocketConfig configures the opening handshake of Upgrade
+type WebSocketConfig struct {
+	// CheckOrigin reports whether the request may open a connection, so that
+	// pages of other sites can't use the cookies of their visitors. By default
+	// the host of the Origin header must be the request host, requests
+	// without Origin come from non-browser clients and are allowed.
+	CheckOrigin func(c *Context) bool
+}
+
+// sameOrigin accepts requests without Origin or whose Origin host is the request host
+func sameOrigin(c *Context) bool {
+	origin := c.Req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, c.Req.Host)
+}
+
+// Upgrade performs the RFC 6455 opening handshake with the default
+// WebSocketConfig, see UpgradeWithConfig
+func (c *Context) Upgrade() (*Conn, error) {
+	return c.UpgradeWithConfig(WebSocketConfig{})
+}
+
+// UpgradeWithConfig performs the RFC 6455 opening handshake and hijacks the
+// connection. On failure it responds with 400 (405 for other methods, 403 for
+// rejected origins, 426 for unsupported versions) and returns the error.
+func (c *Context) UpgradeWithConfig(config WebSocketConfig) (*Conn, error) {
+	fail := func(code int, msg string) (*Conn, error) {
+		c.SetHeader("Sec-WebSocket-Version", "13")
+		c.String(code, "%s\n", msg)
+		return nil, errors.New("gee: websocket: " + msg)
+	}
+	req := c.Req
+	if req.Method != "GET" {
+		return fail(http.StatusMethodNotAllowed, "request method is not GET")
+	}
+	if !headerContainsToken(req.Header, "Connection", "upgrade") ||
+		!headerContainsToken(req.Header, "Upgrade", "websocket") {
+		return fail(http.StatusBadRequest, "missing websocket upgrade headers")
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		return fail(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+	checkOrigin := config.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(c) {
+		return fail(http.StatusForbidden, "request origin not allowed")
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return fail(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+
+	c.Writer.WriteHeader(http.StatusSwitchingProtocols)
+	netConn, rw, err := c.Writer.Hijack()
+	if err != nil {
+		return fail(http.StatusInternalServerError, err.Error())
+	}
+	if rw.Reader.Buffered() > 0 {
+		netConn.Close()
+		return nil, errors.New("gee: websocket: client sent data before handshake completed")
+	}
+
+	handshake := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + computeAcceptKey(key) + "\r\n\r\n"
+	if _, err := netConn.Write([]byte(handshake)); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	return newConn(netConn, rw.Reader, true), nil
+}
+
+// WebSocket returns a handler upgrading the request and running handler
+// with the connection, which is closed once handler returns
+func WebSocket(handler func(*Conn)) HandlerFunc {
+	return WebSocketWithConfig(WebSocketConfig{}, handler)
+}
+
+// WebSocketWithConfig is WebSocket with the handshake configured by config
+func WebSocketWithConfig(config WebSocketConfig, handler func(*Conn)) HandlerFunc {
+	return func(c *Context) {
+		conn, err := c.UpgradeWithConfig(config)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handler(conn)
+	}
+}
+
+// SetReadLimit sets the maximum size in bytes of a message read from the peer,
+// the connection is closed with CloseMessageTooBig when it is exceeded
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readLimit = limit
+}
+
+// SetPingHandler sets the handler for ping frames, the default one replies with a pong
+func (c *Conn) SetPingHandler(h func(data []byte) error) {
+	c.pingHandler = h
+}
+
+// SetPongHandler sets the handler for pong frames, the default one does nothing
+func (c *Conn) SetPongHandler(h func(data []byte) error) {
+	c.pongHandler = h
+}
+
+// SetReadDeadline sets the deadline of the underlying connection for reads
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline of the underlying connection for writes
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// RemoteAddr returns the remote network address
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// writeFrame writes a single frame, client frames are masked
+func (c *Conn) writeFrame(fin bool, opcode int, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrCloseSent
+	}
+
+	header := make([]byte, 2, 14)
+	header[0] = byte(opcode)
+	if fin {
+		header[0] |= 0x80
+	}
+	n := len(payload)
+	switch {
+	case n <= 125:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = append(header, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header[1] = 127
+		header = append(header, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	if !c.isServer {
+		header[1] |= 0x80
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		header = append(header, key[:]...)
+		masked := make([]byte, n)
+		for i := range payload {
+			masked[i] = payload[i] ^ key[i%4]
+		}
+		payload = masked
+	}
+
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WriteMessage writes data as a single text or binary frame
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return c.WriteControl(messageType, data)
+	}
+	return c.writeFrame(true, messageType, data)
+}
+
+// WriteControl writes a close, ping or pong frame, the payload is at most 125 bytes
+func (c *Conn) WriteControl(messageType int, data []byte) error {
+	if messageType != CloseMessage && messageType != PingMessage && messageType != PongMessage {
+		return fmt.Errorf("gee: websocket: invalid control message type %d", messageType)
+	}
+	if len(data) > maxControlPayload {
+		return errors.New("gee: websocket: control frame payload too large")
+	}
+	return c.writeFrame(true, messageType, data)
+}
+
+// Ping sends a ping frame, the peer answers with a pong carrying data
+func (c *Conn) Ping(data []byte) error {
+	return c.WriteControl(PingMessage, data)
+}
+
+func formatClose(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return nil
+	}
+	buf := make([]byte, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	copy(buf[2:], text)
+	return buf
+}
+
+// WriteClose sends a close frame with code and text, no more messages can be written after it
+func (c *Conn) WriteClose(code int, text string) error {
+	return c.WriteControl(CloseMessage, formatClose(code, text))
+}
+
+// Close sends a normal close frame if none was sent yet and closes the connection
+func (c *Conn) Close() error {
+	c.WriteClose(CloseNormalClosure, "")
+	return c.conn.Close()
+}
+
+// messageWriter sends every Write as a fragment of one message
+type messageWriter struct {
+	c           *Conn
+	messageType int
+	started     bool
+	closed      bool
+}
+
+// NextWriter returns a writer for a fragmented message, each Write is sent as
+// one frame and Close sends the final frame. Control frames may be interleaved.
+func (c *Conn) NextWriter(messageType int) (io.WriteCloser, error) {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return nil, fmt.Errorf("gee: websocket: invalid data message type %d", messageType)
+	}
+	return &messageWriter{c: c, messageType: messageType}, nil
+}
+
+func (w *messageWriter) opcode() int {
+	if w.started {
+		return continuationFrame
+	}
+	w.started = true
+	return w.messageType
+}
+
+func (w *messageWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("gee: websocket: write to closed writer")
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if err := w.c.writeFrame(false, w.opcode(), p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *messageWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.c.writeFrame(true, w.opcode(), nil)
+}
+
+// frame is a decoded frame header plus its unmasked payload
+type frame struct {
+	fin     bool
+	opcode  int
+	payload []byte
+}
+
+func (c *Conn) readFrame(remaining int64) (*frame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return nil, err
+	}
+	f := &frame{fin: head[0]&0x80 != 0, opcode: int(head[0] & 0x0f)}
+	if head[0]&0x70 != 0 {
+		return nil, c.protocolError(CloseProtocolError, "reserved bits set")
+	}
+	masked := head[1]&0x80 != 0
+	if masked != c.isServer {
+		return nil, c.protocolError(CloseProtocolError, "bad frame masking")
+	}
+
+	n := int64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return nil, err
+		}
+		n = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return nil, err
+		}
+		n = int64(binary.BigEndian.Uint64(ext[:]))
+		if n < 0 {
+			return nil, c.protocolError(CloseProtocolError, "invalid payload length")
+		}
+	}
+
+	isControl := f.opcode >= CloseMessage
+	switch {
+	case isControl && (n > maxControlPayload || !f.fin):
+		return nil, c.protocolError(CloseProtocolError, "invalid control frame")
+	case f.opcode > BinaryMessage && !isControl, f.opcode > PongMessage:
+		return nil, c.protocolError(CloseProtocolError, fmt.Sprintf("unknown opcode %d", f.opcode))
+	case !isControl && n > remaining:
+		c.WriteClose(CloseMessageTooBig, "")
+		return nil, ErrReadLimit
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, key[:]); err != nil {
+			return nil, err
+		}
+	}
+	f.payload = make([]byte, n)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return nil, err
+	}
+	if masked {
+		for i := range f.payload {
+			f.payload[i] ^= key[i%4]
+		}
+	}
+	return f, nil
+}
+
+// protocolError closes the connection with code and returns the matching error
+func (c *Conn) protocolError(code int, msg string) error {
+	c.WriteClose(code, msg)
+	return fmt.Errorf("gee: websocket: %s", msg)
+}
+
+// ReadMessage reads the next complete text or binary message, reassembling
+// fragments and handling interleaved control frames. A close frame from the
+// peer is answered and returned as *CloseError.
+func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	defer func() {
+		if err != nil {
+			c.readErr = err
+		}
+	}()
+
+	for {
+		f, err := c.readFrame(c.readLimit - int64(len(data)))
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch f.opcode {
+		case PingMessage:
+			if err := c.pingHandler(f.payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if err := c.pongHandler(f.payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.handleClose(f.payload)
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, c.protocolError(CloseProtocolError, "continuation without a started message")
+			}
+		default:
+			if messageType != 0 {
+				return 0, nil, c.protocolError(CloseProtocolError, "new message before the previous one finished")
+			}
+			messageType = f.opcode
+		}
+
+		data = append(data, f.payload...)
+		if f.fin {
+			break
+		}
+	}
+
+	if messageType == TextMessage && !utf8.Valid(data) {
+		return 0, nil, c.protocolError(CloseInvalidFramePayloadData, "invalid UTF-8 in text message")
+	}
+	return messageType, data, nil
+}
+
+// handleClose echoes the close code of the peer and returns it as *CloseError
+func (c *Conn) handleClose(payload []byte) error {
+	e := &CloseError{Code: CloseNoStatusReceived}
+	switch {
+	case len(payload) == 1:
+		return c.protocolError(CloseProtocolError, "invalid close payload")
+	case len(payload) >= 2:
+		e.Code = int(binary.BigEndian.Uint16(payload))
+		e.Text = string(payload[2:])
+		if !utf8.ValidString(e.Text) {
+			return c.protocolError(CloseInvalidFramePayloadData, "invalid UTF-8 in close reason")
+		}
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(closeHandshakeDeadline))
+	c.WriteClose(e.Code, "")
+	return e
+}
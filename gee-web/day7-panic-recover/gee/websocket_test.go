@@ -0,0 +1,163 @@
+package gee
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// dialWebSocket performs the client side of the opening handshake against ts
+func dialWebSocket(t *testing.T, ts *httptest.Server, path string) *Conn {
+	conn, br, res := handshakeWebSocket(t, ts, path, ts.URL)
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake should answer 101, got %d", res.StatusCode)
+	}
+	if accept := res.Header.Get("Sec-WebSocket-Accept"); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected Sec-WebSocket-Accept %s", accept)
+	}
+	return newConn(conn, br, false)
+}
+
+// handshakeWebSocket sends an opening handshake from origin to ts
+func handshakeWebSocket(t *testing.T, ts *httptest.Server, path string, origin string) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(ts.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", ts.URL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, br, res
+}
+
+func newWebSocketServer() *httptest.Server {
+	r := New()
+	r.GET("/echo", WebSocket(func(conn *Conn) {
+		conn.SetReadLimit(1024)
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}))
+	return httptest.NewServer(r)
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	ts := newWebSocketServer()
+	defer ts.Close()
+	conn := dialWebSocket(t, ts, "/echo")
+	defer conn.Close()
+
+	conn.WriteMessage(TextMessage, []byte("hello gee"))
+	messageType, data, err := conn.ReadMessage()
+	if err != nil || messageType != TextMessage || string(data) != "hello gee" {
+		t.Fatalf("unexpected echo %d %q %v", messageType, data, err)
+	}
+
+	// fragmented binary message with a ping interleaved
+	payload := bytes.Repeat([]byte{0xff}, 300)
+	w, _ := conn.NextWriter(BinaryMessage)
+	w.Write(payload[:100])
+	conn.Ping([]byte("ping"))
+	w.Write(payload[100:])
+	w.Close()
+
+	var pong string
+	conn.SetPongHandler(func(data []byte) error {
+		pong = string(data)
+		return nil
+	})
+	messageType, data, err = conn.ReadMessage()
+	if err != nil || messageType != BinaryMessage || !bytes.Equal(data, payload) {
+		t.Fatalf("fragmented message should be reassembled, got %d %d bytes %v", messageType, len(data), err)
+	}
+	if pong != "ping" {
+		t.Fatalf("server should answer the ping, got %q", pong)
+	}
+}
+
+func TestWebSocketClose(t *testing.T) {
+	ts := newWebSocketServer()
+	defer ts.Close()
+
+	conn := dialWebSocket(t, ts, "/echo")
+	conn.WriteClose(CloseGoingAway, "bye")
+	_, _, err := conn.ReadMessage()
+	if e, ok := err.(*CloseError); !ok || e.Code != CloseGoingAway {
+		t.Fatalf("server should echo the close code, got %v", err)
+	}
+	conn.Close()
+
+	conn = dialWebSocket(t, ts, "/echo")
+	defer conn.Close()
+	conn.WriteMessage(BinaryMessage, make([]byte, 2048))
+	_, _, err = conn.ReadMessage()
+	if e, ok := err.(*CloseError); !ok || e.Code != CloseMessageTooBig {
+		t.Fatalf("messages over the read limit should close with 1009, got %v", err)
+	}
+}
+
+func TestWebSocketBadHandshake(t *testing.T) {
+	ts := newWebSocketServer()
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("plain GET should be rejected with 400, got %d", res.StatusCode)
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	ts := newWebSocketServer()
+	defer ts.Close()
+
+	conn, _, res := handshakeWebSocket(t, ts, "/echo", "https://evil.example")
+	conn.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("foreign origin should be rejected with 403, got %d", res.StatusCode)
+	}
+	conn, _, res = handshakeWebSocket(t, ts, "/echo", "")
+	conn.Close()
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("request without origin should be accepted, got %d", res.StatusCode)
+	}
+
+	r := New()
+	r.GET("/ws", WebSocketWithConfig(WebSocketConfig{CheckOrigin: func(c *Context) bool {
+		return c.Req.Header.Get("Origin") == "https://app.example"
+	}}, func(conn *Conn) {}))
+	ts2 := httptest.NewServer(r)
+	defer ts2.Close()
+	conn, _, res = handshakeWebSocket(t, ts2, "/ws", "https://app.example")
+	conn.Close()
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("origin allowed by CheckOrigin should be accepted, got %d", res.StatusCode)
+	}
+}
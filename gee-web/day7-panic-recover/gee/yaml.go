@@ -0,0 +1,195 @@
+package gee
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// marshalYAML encodes v as a block style YAML document. Structs are encoded as
+// mappings using the `yaml` tag, map keys are sorted, time.Time and
+// encoding.TextMarshaler values are encoded as strings.
+func marshalYAML(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeYAML(&buf, reflect.ValueOf(v), 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeYAML(buf *bytes.Buffer, v reflect.Value, indent int) error {
+	v = indirect(v)
+	if s, ok, err := yamlScalar(v); err != nil || ok {
+		if err == nil {
+			buf.WriteString(s + "\n")
+		}
+		return err
+	}
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		return writeYAMLSequence(buf, v, indent)
+	}
+	return writeYAMLMapping(buf, v, indent)
+}
+
+// yamlScalar returns the inline form of v, which is a scalar or an empty collection
+func yamlScalar(v reflect.Value) (string, bool, error) {
+	if !v.IsValid() {
+		return "null", true, nil
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339Nano), true, nil
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return yamlString(string(b)), true, err
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), true, nil
+	case reflect.String:
+		return yamlString(v.String()), true, nil
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			return "[]", true, nil
+		}
+	case reflect.Map:
+		if v.Len() == 0 {
+			return "{}", true, nil
+		}
+	case reflect.Struct:
+		if len(encodedFields(v.Type(), "yaml")) == 0 {
+			return "{}", true, nil
+		}
+	default:
+		return "", false, fmt.Errorf("gee: can't encode %s as YAML", v.Type())
+	}
+	return "", false, nil
+}
+
+// yamlString quotes s when it would otherwise be read as another type or break the syntax
+func yamlString(s string) string {
+	switch strings.ToLower(s) {
+	case "", "~", "null", "true", "false", "yes", "no", "on", "off", "y", "n":
+		return strconv.Quote(s)
+	}
+	if yamlNumeric(s) {
+		return strconv.Quote(s)
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`. ") || strings.HasSuffix(s, " ") || strings.HasSuffix(s, ":") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.ContainsAny(s, "\n\r\t\\") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// yamlNumeric reports whether s may be read as a number by a YAML 1.1 or 1.2
+// parser: decimal, hex, octal or binary, with "_" separators or base 60
+// parts, as well as .inf and .nan. It errs on the side of quoting.
+func yamlNumeric(s string) bool {
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	if !strings.ContainsAny(s[:1], "0123456789+-.") {
+		return false
+	}
+	switch strings.ToLower(strings.TrimLeft(s, "+-")) {
+	case ".inf", ".nan":
+		return true
+	}
+	for i := 0; i < len(s); i++ {
+		if !strings.ContainsRune("0123456789abcdefABCDEFxXoO_.:+-", rune(s[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// writeYAMLEntry writes prefix followed by v, inline for scalars or as a nested block
+func writeYAMLEntry(buf *bytes.Buffer, prefix string, v reflect.Value, indent int) error {
+	v = indirect(v)
+	s, ok, err := yamlScalar(v)
+	if err != nil {
+		return err
+	}
+	if ok {
+		buf.WriteString(prefix + " " + s + "\n")
+		return nil
+	}
+	buf.WriteString(prefix + "\n")
+	return writeYAML(buf, v, indent)
+}
+
+func writeYAMLMapping(buf *bytes.Buffer, v reflect.Value, indent int) error {
+	pad := strings.Repeat(" ", indent)
+	if v.Kind() == reflect.Map {
+		keys := v.MapKeys()
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = fmt.Sprint(k.Interface())
+		}
+		sort.Sort(byName{names, keys})
+		for i, k := range keys {
+			if err := writeYAMLEntry(buf, pad+yamlString(names[i])+":", v.MapIndex(k), indent+2); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, f := range encodedFields(v.Type(), "yaml") {
+		fv := v.Field(f.index)
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+		if err := writeYAMLEntry(buf, pad+yamlString(f.name)+":", fv, indent+2); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeYAMLSequence(buf *bytes.Buffer, v reflect.Value, indent int) error {
+	pad := strings.Repeat(" ", indent)
+	for i := 0; i < v.Len(); i++ {
+		item := indirect(v.Index(i))
+		s, ok, err := yamlScalar(item)
+		if err != nil {
+			return err
+		}
+		if ok {
+			buf.WriteString(pad + "- " + s + "\n")
+			continue
+		}
+		// a nested block starts on the dash line: "- key: value"
+		var nested bytes.Buffer
+		if err := writeYAML(&nested, item, indent+2); err != nil {
+			return err
+		}
+		buf.WriteString(pad + "- ")
+		buf.Write(nested.Bytes()[indent+2:])
+	}
+	return nil
+}
+
+// byName sorts map keys by their string form
+type byName struct {
+	names []string
+	keys  []reflect.Value
+}
+
+func (b byName) Len() int           { return len(b.names) }
+func (b byName) Less(i, j int) bool { return b.names[i] < b.names[j] }
+func (b byName) Swap(i, j int) {
+	b.names[i], b.names[j] = b.names[j], b.names[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
@@ -1,6 +1,6 @@
 module example
 
-go 1.13
+go 1.21
 
 require gee v0.0.0
 
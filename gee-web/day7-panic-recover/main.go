@@ -4,14 +4,16 @@ package main
 $ curl "http://localhost:9999"
 Hello Geektutu
 $ curl "http://localhost:9999/panic"
-{"message":"Internal Server Error"}
+{"instance":"/panic","status":500,"title":"Internal Server Error","type":"about:blank"}
 $ curl "http://localhost:9999"
 Hello Geektutu
 
 >>> log
-2020/01/09 01:00:10 Route  GET - /
-2020/01/09 01:00:10 Route  GET - /panic
-2020/01/09 01:00:22 [200] / in 25.364µs
+2020/01/09 01:00:10 Routes:
+METHOD  PATTERN  NAME  HANDLER          MIDDLEWARES
+GET     /        -     main.main.func1  2
+GET     /panic   -     main.main.func2  2
+time=2020-01-09T01:00:22.000+08:00 level=INFO msg=access method=GET path=/ route=/ status=200 latency=25.364µs bytes_in=0 bytes_out=14 client_ip=::1 user_agent=curl/7.54.0 request_id=""
 2020/01/09 01:00:32 runtime error: index out of range
 Traceback:
         /usr/local/Cellar/go/1.12.5/libexec/src/runtime/panic.go:523
@@ -28,8 +30,8 @@ Traceback:
         /usr/local/Cellar/go/1.12.5/libexec/src/net/http/server.go:1879
         /usr/local/Cellar/go/1.12.5/libexec/src/runtime/asm_amd64.s:1338
 
-2020/01/09 01:00:32 [500] /panic in 395.846µs
-2020/01/09 01:00:38 [200] / in 6.985µs
+time=2020-01-09T01:00:32.000+08:00 level=ERROR msg=access method=GET path=/panic route=/panic status=500 latency=395.846µs bytes_in=0 bytes_out=87 client_ip=::1 user_agent=curl/7.54.0 request_id=""
+time=2020-01-09T01:00:38.000+08:00 level=INFO msg=access method=GET path=/ route=/ status=200 latency=6.985µs bytes_in=0 bytes_out=14 client_ip=::1 user_agent=curl/7.54.0 request_id=""
 */
 
 import (